@@ -1,9 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"warike/base/internal/catalog"
+	"warike/base/internal/providers"
+	"warike/base/internal/ui"
 )
 
 // We can't easily call main() because it calls os.Exit.
@@ -76,3 +89,431 @@ func TestDirectoryCheck_Logic(t *testing.T) {
 		t.Error("New dir should not exist yet")
 	}
 }
+
+func TestCheckTargetDir(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := checkTargetDir(filepath.Join(tmpDir, "new-project")); err != nil {
+		t.Errorf("Expected a missing directory to be accepted, got %v", err)
+	}
+	if err := checkTargetDir(tmpDir); err != nil {
+		t.Errorf("Expected an empty directory to be accepted, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.tf"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkTargetDir(tmpDir); err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Errorf("Expected a non-empty directory to be rejected, got %v", err)
+	}
+}
+
+func TestCreateProject_NonInteractive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"version": "5.0.0"}`))
+	}))
+	defer server.Close()
+	tmpDir := t.TempDir()
+	index := filepath.Join(tmpDir, "index.json")
+	if err := os.WriteFile(index, []byte(`{"versions": {"1.9.2": {}, "1.10.1": {}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	client := &providers.Client{BaseURL: server.URL, HTTPClient: server.Client(), Releases: index}
+
+	scripted := filepath.Join(tmpDir, "scripted")
+	if err := createProject(t.Context(), client, catalog.Embedded(), scripted, createOptions{Providers: []string{"aws", "github"}}); err != nil {
+		t.Fatalf("createProject failed: %v", err)
+	}
+
+	// Generate the same selection through the TUI model for comparison.
+	interactive := filepath.Join(tmpDir, "interactive")
+	m := ui.InitialModel(t.Context(), interactive)
+	m.Loading = false
+	m.RequiredVersion = ">= 1.10"
+	for i := range m.Providers {
+		m.Providers[i].LatestVersion = "5.0.0"
+		m.Selected[i] = m.Providers[i].Name == "aws" || m.Providers[i].Name == "github"
+	}
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'g'}})
+	m = next.(ui.Model)
+
+	for _, f := range []string{"provider.tf", "variables.tf", "main.tf", "terraform.tfvars"} {
+		want, err := os.ReadFile(filepath.Join(interactive, f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(scripted, f))
+		if err != nil {
+			t.Fatalf("Expected %s to be generated: %v", f, err)
+		}
+		// The project name follows the directory name.
+		if f == "terraform.tfvars" {
+			want = []byte(strings.Replace(string(want), "interactive", "scripted", 1))
+		}
+		if string(got) != string(want) {
+			t.Errorf("%s differs from the TUI output:\n%s\nwant:\n%s", f, got, want)
+		}
+		if f == "provider.tf" && !strings.Contains(string(got), `required_version = ">= 1.10"`) {
+			t.Errorf("provider.tf lacks the required_version of the releases index:\n%s", got)
+		}
+	}
+}
+
+func TestHandleCreate_FlagsAfterDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, "cache"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+
+	mirror := t.TempDir()
+	files := map[string]string{
+		"registry.terraform.io/hashicorp/aws/index.json":       `{"versions": {"5.0.0": {}}}`,
+		"registry.terraform.io/integrations/github/index.json": `{"versions": {"6.0.0": {}}}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(mirror, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	releases := filepath.Join(home, "releases.json")
+	if err := os.WriteFile(releases, []byte(`{"versions": {"1.10.1": {}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "my-infra")
+	handleCreate([]string{dir, "--providers", "aws,github", "--yes", "--mirror", mirror, "--releases", releases}, 0)
+
+	got, err := os.ReadFile(filepath.Join(dir, "provider.tf"))
+	if err != nil {
+		t.Fatalf("Expected the project to be created in %s: %v", dir, err)
+	}
+	for _, s := range []string{`source  = "hashicorp/aws"`, `source  = "integrations/github"`, `required_version = ">= 1.10"`} {
+		if !strings.Contains(string(got), s) {
+			t.Errorf("provider.tf lacks %s:\n%s", s, got)
+		}
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args       []string
+		positional []string
+		name       string
+		yes        bool
+	}{
+		{[]string{"--yes", "dir"}, []string{"dir"}, "", true},
+		{[]string{"dir", "--name", "x", "--yes"}, []string{"dir"}, "x", true},
+		{[]string{"a", "--yes", "b"}, []string{"a", "b"}, "", true},
+		{[]string{"a", "--", "--yes"}, []string{"a", "--yes"}, "", false},
+		{nil, nil, "", false},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		name := fs.String("name", "", "")
+		yes := fs.Bool("yes", false, "")
+		got := parseArgs(fs, tt.args)
+		if !slices.Equal(got, tt.positional) || *name != tt.name || *yes != tt.yes {
+			t.Errorf("parseArgs(%q) = %q, name %q, yes %v; want %q, %q, %v", tt.args, got, *name, *yes, tt.positional, tt.name, tt.yes)
+		}
+	}
+}
+
+// roundTripFunc is an http.RoundTripper calling itself.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestCreateProject_MirrorStaysOffline(t *testing.T) {
+	mirror := t.TempDir()
+	index := filepath.Join(mirror, "registry.terraform.io", "hashicorp", "aws", "index.json")
+	if err := os.MkdirAll(filepath.Dir(index), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(index, []byte(`{"versions": {"5.0.0": {}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	offline := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		t.Errorf("Unexpected request to %s", r.URL)
+		return nil, errors.New("offline")
+	})}
+	client := &providers.Client{BaseURL: providers.DefaultRegistryURL, HTTPClient: offline, Mirror: mirror}
+
+	dir := filepath.Join(t.TempDir(), "p")
+	if err := createProject(t.Context(), client, catalog.Embedded(), dir, createOptions{Providers: []string{"aws"}}); err != nil {
+		t.Fatalf("createProject failed: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "provider.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(got), "required_version") {
+		t.Errorf("provider.tf has a required_version without a releases index:\n%s", got)
+	}
+}
+
+func TestCreateProject_Errors(t *testing.T) {
+	client := &providers.Client{BaseURL: "http://127.0.0.1:0"}
+	dir := filepath.Join(t.TempDir(), "p")
+
+	if err := createProject(t.Context(), client, catalog.Embedded(), dir, createOptions{}); err == nil {
+		t.Error("Expected error when no providers are given")
+	}
+	if err := createProject(t.Context(), client, catalog.Embedded(), dir, createOptions{Providers: []string{"nope"}}); err == nil || !strings.Contains(err.Error(), "unknown provider") {
+		t.Errorf("Expected unknown provider error, got %v", err)
+	}
+	aliases := aliasList{}
+	aliases.Set("google.eu,region=europe-west1")
+	if err := createProject(t.Context(), client, catalog.Embedded(), dir, createOptions{Providers: []string{"aws"}, Aliases: aliases}); err == nil || !strings.Contains(err.Error(), "not among the providers") {
+		t.Errorf("Expected an error for an alias of an unselected provider, got %v", err)
+	}
+}
+
+func TestAddRemoveProviders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"version": "5.0.0"}`))
+	}))
+	defer server.Close()
+	client := &providers.Client{BaseURL: server.URL, HTTPClient: server.Client(), Releases: "missing.json"}
+
+	dir := filepath.Join(t.TempDir(), "p")
+	if err := createProject(t.Context(), client, catalog.Embedded(), dir, createOptions{Providers: []string{"aws"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := addProviders(t.Context(), client, catalog.Embedded(), dir, []string{"cloudflare", "vercel"}); err != nil {
+		t.Fatalf("addProviders failed: %v", err)
+	}
+	provider, _ := os.ReadFile(filepath.Join(dir, "provider.tf"))
+	for _, s := range []string{`source  = "cloudflare/cloudflare"`, `provider "vercel" {`} {
+		if !strings.Contains(string(provider), s) {
+			t.Errorf("provider.tf lacks %s:\n%s", s, provider)
+		}
+	}
+
+	if err := removeProviders(catalog.Embedded(), dir, []string{"vercel"}); err != nil {
+		t.Fatalf("removeProviders failed: %v", err)
+	}
+	provider, _ = os.ReadFile(filepath.Join(dir, "provider.tf"))
+	if strings.Contains(string(provider), "vercel") {
+		t.Errorf("provider.tf still mentions vercel:\n%s", provider)
+	}
+
+	if err := removeProviders(catalog.Embedded(), dir, []string{"nope"}); err == nil || !strings.Contains(err.Error(), "unknown provider") {
+		t.Errorf("Expected unknown provider error, got %v", err)
+	}
+}
+
+func TestParseBackend(t *testing.T) {
+	backend, err := parseBackend("s3", []string{"bucket=acme-state", "region=eu-west-1"})
+	if err != nil {
+		t.Fatalf("parseBackend failed: %v", err)
+	}
+	if backend.Type != "s3" || backend.Config["bucket"] != "acme-state" || backend.Config["region"] != "eu-west-1" {
+		t.Errorf("Unexpected backend %+v", backend)
+	}
+
+	if _, err := parseBackend("s3", []string{"bucket"}); err == nil {
+		t.Error("Expected error for a setting without a value")
+	}
+	if _, err := parseBackend("none", []string{"bucket=x"}); err == nil {
+		t.Error("Expected error for settings without a backend")
+	}
+}
+
+func TestE2E_BackendStep(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "infra")
+	m := ui.InitialModel(t.Context(), dir)
+	m.Loading = false
+	m.ChooseBackend = true
+	m.Environments = []string{"dev", "prod"}
+	m.Providers = []ui.Provider{{Name: "aws", Source: "hashicorp/aws", LatestVersion: "5.0.0"}}
+	m.Selected = []bool{true}
+
+	keys := []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune{'g'}},
+		{Type: tea.KeyDown},
+		{Type: tea.KeyDown},
+		{Type: tea.KeyEnter},
+	}
+	for i, key := range keys {
+		next, _ := m.Update(key)
+		m = next.(ui.Model)
+		if i == 0 && (!m.ChoosingBackend || m.FilesGenerated) {
+			t.Fatal("Expected 'g' to open the backend step")
+		}
+	}
+
+	if !m.FilesGenerated || m.Error != "" {
+		t.Fatalf("Expected files to be generated, error %q", m.Error)
+	}
+	hcl, err := os.ReadFile(filepath.Join(dir, "envs", "prod.backend.hcl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(hcl), `dynamodb_table = "infra-tflock"`) {
+		t.Errorf("Expected DynamoDB locking in prod.backend.hcl, got:\n%s", hcl)
+	}
+}
+
+func TestE2E_AliasStep(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "infra")
+	aws, _ := catalog.Embedded().Get("aws")
+	m := ui.InitialModel(t.Context(), dir)
+	m.Loading = false
+	m.Providers = []ui.Provider{{Name: "aws", Source: "hashicorp/aws", LatestVersion: "5.0.0", Definition: aws}}
+	m.Selected = []bool{false}
+
+	typeText := func(s string) {
+		for _, r := range s {
+			next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+			m = next.(ui.Model)
+		}
+	}
+	press := func(k tea.KeyType) {
+		next, _ := m.Update(tea.KeyMsg{Type: k})
+		m = next.(ui.Model)
+	}
+
+	typeText("a")
+	typeText("west,zone=b")
+	press(tea.KeyEnter)
+	if !m.EnteringAlias || !strings.Contains(m.AliasError, "unknown setting") {
+		t.Fatalf("Expected an unknown setting to be rejected, error %q", m.AliasError)
+	}
+	for range "zone=b" {
+		press(tea.KeyBackspace)
+	}
+	typeText("region=us-west-1")
+	press(tea.KeyEnter)
+	if m.EnteringAlias || len(m.Providers[0].Aliases) != 1 || !m.Selected[0] {
+		t.Fatalf("Expected the alias to be added and aws selected, got %+v", m.Providers[0].Aliases)
+	}
+
+	typeText("g")
+	if !m.FilesGenerated || m.Error != "" {
+		t.Fatalf("Expected files to be generated, error %q", m.Error)
+	}
+	provider, err := os.ReadFile(filepath.Join(dir, "provider.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(provider), `alias   = "west"`) {
+		t.Errorf("Expected the west alias in provider.tf, got:\n%s", provider)
+	}
+}
+
+func TestE2E_FilterAndGroups(t *testing.T) {
+	m := ui.InitialModel(t.Context(), t.TempDir())
+	m.Loading = false
+	press := func(keys ...tea.KeyMsg) {
+		for _, key := range keys {
+			next, _ := m.Update(key)
+			m = next.(ui.Model)
+		}
+	}
+	runes := func(s string) tea.KeyMsg {
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+	}
+
+	// Providers are grouped by category, cloud first.
+	visible := m.Visible()
+	if first, last := m.Providers[visible[0]], m.Providers[visible[len(visible)-1]]; first.Definition.Category != "cloud" || last.Definition.Category != "utility" {
+		t.Errorf("Expected cloud providers first and utilities last, got %s and %s", first.Name, last.Name)
+	}
+	if view := m.View(); !strings.Contains(view, "Cloud") || !strings.Contains(view, "SaaS") {
+		t.Errorf("Expected category headings in the list:\n%s", view)
+	}
+
+	// Typing after / filters by fuzzy match.
+	press(runes("/"), runes("vrcl"), tea.KeyMsg{Type: tea.KeyEnter})
+	visible = m.Visible()
+	if len(visible) != 1 || m.Providers[visible[0]].Name != "vercel" || m.Cursor != visible[0] {
+		t.Fatalf("Expected the filter to leave vercel under the cursor, got %v", visible)
+	}
+	if view := m.View(); !strings.Contains(view, "source:  vercel/vercel") {
+		t.Errorf("Expected the detail pane of vercel:\n%s", view)
+	}
+
+	press(runes("+"))
+	for i, selected := range m.Selected {
+		if selected != (m.Providers[i].Name == "vercel") {
+			t.Errorf("Expected only the visible vercel to be selected, %s selected = %v", m.Providers[i].Name, selected)
+		}
+	}
+
+	// Clearing the filter shows everything again; - deselects all of it.
+	press(tea.KeyMsg{Type: tea.KeyEsc}, runes("-"))
+	if len(m.Visible()) != len(m.Providers) {
+		t.Errorf("Expected esc to clear the filter")
+	}
+	for i, selected := range m.Selected {
+		if selected {
+			t.Errorf("Expected %s to be deselected", m.Providers[i].Name)
+		}
+	}
+
+	press(runes("/"), runes("zzz"))
+	if view := m.View(); !strings.Contains(view, "No providers match.") {
+		t.Errorf("Expected an empty filter result:\n%s", view)
+	}
+}
+
+func TestE2E_QuitCancelsFetch(t *testing.T) {
+	// A registry that never answers: only canceling ends the lookups.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	m := ui.InitialModel(ctx, t.TempDir())
+	m.Cancel = cancel
+	m.Client = &providers.Client{BaseURL: server.URL, HTTPClient: server.Client()}
+
+	fetched := make(chan error, 1)
+	go func() {
+		_, err := ui.FetchVersions(m.Ctx, m.Client, m.Providers)
+		fetched <- err
+	}()
+
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if cmd == nil || next.(ui.Model).Ctx.Err() == nil {
+		t.Fatal("Expected ctrl+c to quit and cancel the model's context")
+	}
+	select {
+	case err := <-fetched:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("FetchVersions() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("FetchVersions() did not return after quitting")
+	}
+}
+
+func TestContextError(t *testing.T) {
+	ctx, cancel := context.WithTimeoutCause(t.Context(), time.Nanosecond, errors.New("timed out after 1ns"))
+	defer cancel()
+	<-ctx.Done()
+	if err := contextError(ctx, context.DeadlineExceeded); err.Error() != "timed out after 1ns" {
+		t.Errorf("contextError() = %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := contextError(canceled, context.Canceled); err.Error() != "interrupted" {
+		t.Errorf("contextError() = %v", err)
+	}
+
+	if err := contextError(t.Context(), errors.New("boom")); err.Error() != "boom" {
+		t.Errorf("contextError() = %v", err)
+	}
+}
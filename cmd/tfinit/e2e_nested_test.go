@@ -20,7 +20,7 @@ func TestE2E_GenerateFiles_InNestedSubdirectory(t *testing.T) {
 	targetProjectDir := filepath.Join("example", "complete")
 
 	// Initialize the model, passing the RELATIVE nested path
-	m := ui.InitialModel(targetProjectDir)
+	m := ui.InitialModel(t.Context(), targetProjectDir)
 
 	// BYPASS LOADING STATE
 	m.Loading = false
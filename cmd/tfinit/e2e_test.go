@@ -25,7 +25,7 @@ func TestE2E_GenerateFiles_InSubdirectory(t *testing.T) {
 	targetProjectDir := "my-cool-project"
 
 	// Initialize the model, passing the RELATIVE path, just like the real app.
-	m := ui.InitialModel(targetProjectDir)
+	m := ui.InitialModel(t.Context(), targetProjectDir)
 
 	// BYPASS LOADING STATE for a synchronous test
 	m.Loading = false
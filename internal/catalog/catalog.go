@@ -0,0 +1,268 @@
+// Package catalog loads the provider definitions used to scaffold projects.
+//
+// Each provider is described by an HCL data file:
+//
+//	provider "aws" {
+//	  source      = "hashicorp/aws"
+//	  category    = "cloud"
+//	  description = "Amazon Web Services"
+//
+//	  config = <<-EOT
+//	    region = local.aws_region
+//	  EOT
+//
+//	  local "aws_region" {
+//	    value = "var.aws_region"
+//	  }
+//
+//	  variable "aws_region" {
+//	    description = "AWS region"
+//	    type        = "string"
+//	    default     = "us-west-2"
+//	    tfvars      = "us-west-2"
+//	  }
+//
+//	  alias_setting "role_arn" {
+//	    description = "IAM role to assume"
+//	    argument    = "assume_role.role_arn"
+//	  }
+//	}
+//
+// A default set is embedded in the binary. Files found in user directories
+// add providers or replace embedded ones with the same name.
+package catalog
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+//go:embed providers/*.hcl
+var embedded embed.FS
+
+// PathEnv lists extra catalog directories, separated like PATH.
+const PathEnv = "TFINIT_CATALOG_PATH"
+
+// Provider describes how a provider is wired into a generated project.
+type Provider struct {
+	Name        string `hcl:"name,label"`
+	Source      string `hcl:"source"`
+	Category    string `hcl:"category,optional"`
+	Description string `hcl:"description,optional"`
+
+	// Config is the body of the provider block, as raw HCL.
+	Config string `hcl:"config,optional"`
+
+	Locals    []Local    `hcl:"local,block"`
+	Variables []Variable `hcl:"variable,block"`
+
+	// AliasSettings are the arguments that aliased configurations of the
+	// provider can set to their own values.
+	AliasSettings []AliasSetting `hcl:"alias_setting,block"`
+}
+
+// AliasSetting is an argument of an aliased provider configuration, such as
+// another region or an assumed role.
+type AliasSetting struct {
+	Name        string `hcl:"name,label"`
+	Description string `hcl:"description,optional"`
+	// Argument is the provider argument set, with nested blocks separated by
+	// dots as in assume_role.role_arn. It defaults to Name.
+	Argument string `hcl:"argument,optional"`
+}
+
+// Path returns the blocks and argument name that the setting sets.
+func (s AliasSetting) Path() []string {
+	if s.Argument == "" {
+		return []string{s.Name}
+	}
+	return strings.Split(s.Argument, ".")
+}
+
+// AliasSetting returns the alias setting named name.
+func (p Provider) AliasSetting(name string) (AliasSetting, bool) {
+	for _, s := range p.AliasSettings {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return AliasSetting{}, false
+}
+
+// Local is an entry added to the generated locals block.
+type Local struct {
+	Name string `hcl:"name,label"`
+	// Value is the HCL expression assigned to the local.
+	Value string `hcl:"value"`
+}
+
+// Variable is an input variable declared in variables.tf, with an optional
+// placeholder written to terraform.tfvars.
+type Variable struct {
+	Name        string    `hcl:"name,label"`
+	Description string    `hcl:"description,optional"`
+	Type        string    `hcl:"type,optional"`
+	Default     cty.Value `hcl:"default,optional"`
+	Sensitive   bool      `hcl:"sensitive,optional"`
+	Tfvars      cty.Value `hcl:"tfvars,optional"`
+}
+
+// TypeExpr returns the variable type constraint, defaulting to string.
+func (v Variable) TypeExpr() string {
+	if v.Type == "" {
+		return "string"
+	}
+	return v.Type
+}
+
+// DefaultHCL renders the default value as HCL, or "" when there is none.
+func (v Variable) DefaultHCL() string {
+	return renderValue(v.Default)
+}
+
+// TfvarsHCL renders the tfvars placeholder as HCL, or "" when there is none.
+func (v Variable) TfvarsHCL() string {
+	return renderValue(v.Tfvars)
+}
+
+func renderValue(v cty.Value) string {
+	if v == cty.NilVal || v.IsNull() {
+		return ""
+	}
+	return string(hclwrite.TokensForValue(v).Bytes())
+}
+
+// Catalog is an ordered set of provider definitions.
+type Catalog struct {
+	providers []Provider
+}
+
+type catalogFile struct {
+	Providers []Provider `hcl:"provider,block"`
+}
+
+// Embedded returns the catalog built into the binary.
+func Embedded() *Catalog {
+	c := &Catalog{}
+	if err := c.loadFS(embedded, "providers"); err != nil {
+		panic(fmt.Sprintf("catalog: invalid embedded definitions: %v", err))
+	}
+	return c
+}
+
+// Load returns the embedded catalog extended with the definitions found in
+// dirs. Later directories take precedence over earlier ones; directories
+// that do not exist are ignored.
+func Load(dirs ...string) (*Catalog, error) {
+	c := Embedded()
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		if err := c.loadFS(os.DirFS(dir), "."); err != nil {
+			return nil, fmt.Errorf("loading catalog %s: %w", dir, err)
+		}
+	}
+	return c, nil
+}
+
+// UserDirs returns the directories searched for user definitions: the
+// tfinit/providers directory under the user configuration directory, then
+// every entry of TFINIT_CATALOG_PATH.
+func UserDirs() []string {
+	var dirs []string
+	if base, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(base, "tfinit", "providers"))
+	}
+	for _, dir := range filepath.SplitList(os.Getenv(PathEnv)) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+func (c *Catalog) loadFS(fsys fs.FS, dir string) error {
+	paths, err := fs.Glob(fsys, pathJoin(dir, "*.hcl"))
+	if err != nil {
+		return err
+	}
+
+	parser := hclparse.NewParser()
+	for _, path := range paths {
+		src, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		file, diags := parser.ParseHCL(src, path)
+		if diags.HasErrors() {
+			return diags
+		}
+
+		var decoded catalogFile
+		if diags := gohcl.DecodeBody(file.Body, nil, &decoded); diags.HasErrors() {
+			return diags
+		}
+		for _, p := range decoded.Providers {
+			c.add(p)
+		}
+	}
+
+	sort.SliceStable(c.providers, func(i, j int) bool {
+		return c.providers[i].Name < c.providers[j].Name
+	})
+	return nil
+}
+
+func pathJoin(dir, name string) string {
+	if dir == "." {
+		return name
+	}
+	return dir + "/" + name
+}
+
+// add inserts p, replacing any provider with the same name.
+func (c *Catalog) add(p Provider) {
+	p.Config = strings.TrimSpace(p.Config)
+	for i := range c.providers {
+		if c.providers[i].Name == p.Name {
+			c.providers[i] = p
+			return
+		}
+	}
+	c.providers = append(c.providers, p)
+}
+
+// Providers returns every definition, sorted by name.
+func (c *Catalog) Providers() []Provider {
+	return append([]Provider(nil), c.providers...)
+}
+
+// Get returns the definition named name.
+func (c *Catalog) Get(name string) (Provider, bool) {
+	for _, p := range c.providers {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Provider{}, false
+}
+
+// Names returns the provider names in catalog order.
+func (c *Catalog) Names() []string {
+	names := make([]string, len(c.providers))
+	for i, p := range c.providers {
+		names[i] = p.Name
+	}
+	return names
+}
@@ -0,0 +1,106 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmbedded(t *testing.T) {
+	c := Embedded()
+
+	for _, name := range []string{"aws", "google", "azurerm", "github", "vercel", "cloudflare", "kubernetes", "helm", "datadog", "random"} {
+		if _, ok := c.Get(name); !ok {
+			t.Errorf("Expected embedded catalog to define %q", name)
+		}
+	}
+
+	aws, _ := c.Get("aws")
+	if aws.Source != "hashicorp/aws" || aws.Category != "cloud" {
+		t.Errorf("Unexpected aws definition: %+v", aws)
+	}
+	if len(aws.Locals) != 2 || aws.Locals[0].Name != "aws_region" || aws.Locals[0].Value != "var.aws_region" {
+		t.Errorf("Unexpected aws locals: %+v", aws.Locals)
+	}
+	region := aws.Variables[0]
+	if region.DefaultHCL() != `"us-west-2"` || region.TfvarsHCL() != `"us-west-2"` || region.TypeExpr() != "string" {
+		t.Errorf("Unexpected aws_region variable rendering: %q %q %q", region.DefaultHCL(), region.TfvarsHCL(), region.TypeExpr())
+	}
+
+	role, ok := aws.AliasSetting("role_arn")
+	if !ok || strings.Join(role.Path(), ".") != "assume_role.role_arn" {
+		t.Errorf("Unexpected aws role_arn alias setting: %+v", role)
+	}
+	if region, _ := aws.AliasSetting("region"); strings.Join(region.Path(), ".") != "region" {
+		t.Errorf("Expected the region alias setting to default to its name, got %v", region.Path())
+	}
+
+	google, _ := c.Get("google")
+	if google.Variables[0].DefaultHCL() != "" {
+		t.Errorf("Expected google_project_id to have no default, got %q", google.Variables[0].DefaultHCL())
+	}
+}
+
+func TestLoad_UserDirectory(t *testing.T) {
+	dir := t.TempDir()
+	content := `
+provider "aws" {
+  source = "hashicorp/aws"
+  config = "region = \"eu-west-1\""
+}
+
+provider "tls" {
+  source   = "hashicorp/tls"
+  category = "utility"
+
+  variable "tls_algorithm" {
+    type    = "string"
+    default = "ED25519"
+  }
+
+  variable "tls_bits" {
+    type   = "number"
+    tfvars = 4096
+  }
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "custom.hcl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load(filepath.Join(dir, "missing"), dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	aws, _ := c.Get("aws")
+	if aws.Config != `region = "eu-west-1"` || len(aws.Variables) != 0 {
+		t.Errorf("Expected user definition to replace embedded aws, got %+v", aws)
+	}
+
+	tls, ok := c.Get("tls")
+	if !ok {
+		t.Fatal("Expected tls to be added")
+	}
+	if tls.Variables[1].TfvarsHCL() != "4096" {
+		t.Errorf("TfvarsHCL() = %q, want 4096", tls.Variables[1].TfvarsHCL())
+	}
+
+	names := c.Names()
+	for i := 1; i < len(names); i++ {
+		if names[i-1] > names[i] {
+			t.Errorf("Catalog not sorted: %v", names)
+		}
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.hcl"), []byte(`provider "x" {}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Error("Expected error for a provider without source")
+	}
+}
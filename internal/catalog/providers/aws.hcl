@@ -0,0 +1,44 @@
+provider "aws" {
+  source      = "hashicorp/aws"
+  category    = "cloud"
+  description = "Amazon Web Services"
+
+  config = <<-EOT
+    region  = local.aws_region
+    profile = local.aws_profile
+    default_tags {
+      tags = local.tags
+    }
+  EOT
+
+  local "aws_region" {
+    value = "var.aws_region"
+  }
+
+  local "aws_profile" {
+    value = "var.aws_profile"
+  }
+
+  variable "aws_region" {
+    description = "AWS region"
+    type        = "string"
+    default     = "us-west-2"
+    tfvars      = "us-west-2"
+  }
+
+  variable "aws_profile" {
+    description = "AWS profile name"
+    type        = "string"
+    default     = "default"
+    tfvars      = "default"
+  }
+
+  alias_setting "region" {
+    description = "AWS region"
+  }
+
+  alias_setting "role_arn" {
+    description = "IAM role to assume"
+    argument    = "assume_role.role_arn"
+  }
+}
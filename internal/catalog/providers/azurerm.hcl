@@ -0,0 +1,36 @@
+provider "azurerm" {
+  source      = "hashicorp/azurerm"
+  category    = "cloud"
+  description = "Microsoft Azure Resource Manager"
+
+  config = <<-EOT
+    subscription_id = local.azure_subscription_id
+    features {}
+  EOT
+
+  local "azure_location" {
+    value = "var.azure_location"
+  }
+
+  local "azure_subscription_id" {
+    value = "var.azure_subscription_id"
+  }
+
+  variable "azure_location" {
+    description = "Azure location"
+    type        = "string"
+    default     = "East US"
+    tfvars      = "East US"
+  }
+
+  variable "azure_subscription_id" {
+    description = "Azure subscription ID"
+    type        = "string"
+    sensitive   = true
+    tfvars      = "azure-subscription-id-goes-here"
+  }
+
+  alias_setting "subscription_id" {
+    description = "Azure subscription ID"
+  }
+}
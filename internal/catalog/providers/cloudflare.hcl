@@ -0,0 +1,20 @@
+provider "cloudflare" {
+  source      = "cloudflare/cloudflare"
+  category    = "saas"
+  description = "Cloudflare DNS, CDN and security settings"
+
+  config = <<-EOT
+    api_token = local.cloudflare_api_token
+  EOT
+
+  local "cloudflare_api_token" {
+    value = "var.cloudflare_api_token"
+  }
+
+  variable "cloudflare_api_token" {
+    description = "Cloudflare API Token"
+    type        = "string"
+    sensitive   = true
+    tfvars      = "your-cloudflare-token"
+  }
+}
@@ -0,0 +1,44 @@
+provider "datadog" {
+  source      = "DataDog/datadog"
+  category    = "saas"
+  description = "Datadog monitors, dashboards and integrations"
+
+  config = <<-EOT
+    api_key = local.datadog_api_key
+    app_key = local.datadog_app_key
+    api_url = local.datadog_api_url
+  EOT
+
+  local "datadog_api_key" {
+    value = "var.datadog_api_key"
+  }
+
+  local "datadog_app_key" {
+    value = "var.datadog_app_key"
+  }
+
+  local "datadog_api_url" {
+    value = "var.datadog_api_url"
+  }
+
+  variable "datadog_api_key" {
+    description = "Datadog API key"
+    type        = "string"
+    sensitive   = true
+    tfvars      = "your-datadog-api-key"
+  }
+
+  variable "datadog_app_key" {
+    description = "Datadog application key"
+    type        = "string"
+    sensitive   = true
+    tfvars      = "your-datadog-app-key"
+  }
+
+  variable "datadog_api_url" {
+    description = "Datadog API URL for your site"
+    type        = "string"
+    default     = "https://api.datadoghq.com/"
+    tfvars      = "https://api.datadoghq.com/"
+  }
+}
@@ -0,0 +1,32 @@
+provider "github" {
+  source      = "integrations/github"
+  category    = "saas"
+  description = "GitHub repositories, teams and organization settings"
+
+  config = <<-EOT
+    owner = local.gh_owner
+    token = local.gh_token
+  EOT
+
+  local "gh_owner" {
+    value = "var.gh_owner"
+  }
+
+  local "gh_token" {
+    value = "var.gh_token"
+  }
+
+  variable "gh_owner" {
+    description = "GitHub owner (user or organization)"
+    type        = "string"
+    default     = "warike"
+    tfvars      = "warike"
+  }
+
+  variable "gh_token" {
+    description = "GitHub token"
+    type        = "string"
+    sensitive   = true
+    tfvars      = "your-github-token"
+  }
+}
@@ -0,0 +1,39 @@
+provider "google" {
+  source      = "hashicorp/google"
+  category    = "cloud"
+  description = "Google Cloud Platform"
+
+  config = <<-EOT
+    project = local.gcp_project_id
+    region  = local.gcp_region
+  EOT
+
+  local "gcp_project_id" {
+    value = "var.google_project_id"
+  }
+
+  local "gcp_region" {
+    value = "var.google_region"
+  }
+
+  variable "google_project_id" {
+    description = "Google Cloud project ID"
+    type        = "string"
+    tfvars      = "gcp-project-id-goes-here"
+  }
+
+  variable "google_region" {
+    description = "Google Cloud region"
+    type        = "string"
+    default     = "us-central1"
+    tfvars      = "us-central1"
+  }
+
+  alias_setting "project" {
+    description = "Google Cloud project ID"
+  }
+
+  alias_setting "region" {
+    description = "Google Cloud region"
+  }
+}
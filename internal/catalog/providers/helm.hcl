@@ -0,0 +1,33 @@
+provider "helm" {
+  source      = "hashicorp/helm"
+  category    = "utility"
+  description = "Helm chart releases"
+
+  config = <<-EOT
+    kubernetes {
+      config_path    = local.helm_kube_config_path
+      config_context = local.helm_kube_config_context
+    }
+  EOT
+
+  local "helm_kube_config_path" {
+    value = "var.helm_kube_config_path"
+  }
+
+  local "helm_kube_config_context" {
+    value = "var.helm_kube_config_context"
+  }
+
+  variable "helm_kube_config_path" {
+    description = "Path to the kubeconfig file used by Helm"
+    type        = "string"
+    default     = "~/.kube/config"
+    tfvars      = "~/.kube/config"
+  }
+
+  variable "helm_kube_config_context" {
+    description = "kubeconfig context used by Helm"
+    type        = "string"
+    tfvars      = "your-kube-context"
+  }
+}
@@ -0,0 +1,31 @@
+provider "kubernetes" {
+  source      = "hashicorp/kubernetes"
+  category    = "utility"
+  description = "Kubernetes cluster resources"
+
+  config = <<-EOT
+    config_path    = local.kube_config_path
+    config_context = local.kube_config_context
+  EOT
+
+  local "kube_config_path" {
+    value = "var.kube_config_path"
+  }
+
+  local "kube_config_context" {
+    value = "var.kube_config_context"
+  }
+
+  variable "kube_config_path" {
+    description = "Path to the kubeconfig file"
+    type        = "string"
+    default     = "~/.kube/config"
+    tfvars      = "~/.kube/config"
+  }
+
+  variable "kube_config_context" {
+    description = "kubeconfig context to use"
+    type        = "string"
+    tfvars      = "your-kube-context"
+  }
+}
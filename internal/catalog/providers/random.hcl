@@ -0,0 +1,5 @@
+provider "random" {
+  source      = "hashicorp/random"
+  category    = "utility"
+  description = "Random values such as IDs, passwords and pets"
+}
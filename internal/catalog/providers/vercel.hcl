@@ -0,0 +1,20 @@
+provider "vercel" {
+  source      = "vercel/vercel"
+  category    = "saas"
+  description = "Vercel projects, deployments and domains"
+
+  config = <<-EOT
+    api_token = local.vercel_api_token
+  EOT
+
+  local "vercel_api_token" {
+    value = "var.vercel_api_token"
+  }
+
+  variable "vercel_api_token" {
+    description = "Vercel API Token"
+    type        = "string"
+    sensitive   = true
+    tfvars      = "your-vercel-token"
+  }
+}
@@ -0,0 +1,82 @@
+// Package config loads the optional .tfinit.hcl settings file.
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+)
+
+// DefaultFile is the name of the settings file looked up in the target
+// directory when no explicit path is given.
+const DefaultFile = ".tfinit.hcl"
+
+// Config holds the settings shared by the tfinit commands.
+//
+//	allow          = "minor"
+//	ignore         = ["examples", "test/*"]
+//	lock_platforms = ["linux_amd64", "darwin_arm64"]
+//	flavor         = "tofu"
+//
+//	provider "hashicorp/aws" {
+//	  allow = "patch"
+//	}
+type Config struct {
+	// Allow is the default update policy: "patch", "minor" or "major".
+	Allow string `hcl:"allow,optional"`
+	// Ignore lists directory globs skipped by recursive updates.
+	Ignore []string `hcl:"ignore,optional"`
+	// LockPlatforms lists the os_arch pairs hashed into
+	// .terraform.lock.hcl when a provider is updated.
+	LockPlatforms []string `hcl:"lock_platforms,optional"`
+	// Flavor is "terraform" (the default) or "tofu", selecting the registry
+	// and the lock file host.
+	Flavor string `hcl:"flavor,optional"`
+
+	Providers []ProviderConfig `hcl:"provider,block"`
+}
+
+// ProviderConfig overrides settings for a single provider, identified by its
+// source address or local name.
+type ProviderConfig struct {
+	Source string `hcl:"source,label"`
+	Allow  string `hcl:"allow,optional"`
+}
+
+// Load parses the settings file at path.
+func Load(path string) (*Config, error) {
+	file, diags := hclparse.NewParser().ParseHCLFile(path)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	var cfg Config
+	if diags := gohcl.DecodeBody(file.Body, nil, &cfg); diags.HasErrors() {
+		return nil, diags
+	}
+	return &cfg, nil
+}
+
+// LoadDefault loads DefaultFile from dir, returning an empty configuration
+// when the file does not exist.
+func LoadDefault(dir string) (*Config, error) {
+	path := filepath.Join(dir, DefaultFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	return Load(path)
+}
+
+// ProviderAllows returns the per-provider policy overrides keyed by source
+// or local name.
+func (c *Config) ProviderAllows() map[string]string {
+	overrides := make(map[string]string)
+	for _, p := range c.Providers {
+		if p.Allow != "" {
+			overrides[p.Source] = p.Allow
+		}
+	}
+	return overrides
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	content := `
+allow  = "minor"
+ignore = ["examples"]
+lock_platforms = ["linux_amd64", "darwin_arm64"]
+
+provider "hashicorp/aws" {
+  allow = "patch"
+}
+
+provider "github" {}
+`
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, DefaultFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadDefault(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadDefault failed: %v", err)
+	}
+
+	if cfg.Allow != "minor" {
+		t.Errorf("Allow = %q, want minor", cfg.Allow)
+	}
+	if len(cfg.Ignore) != 1 || cfg.Ignore[0] != "examples" {
+		t.Errorf("Ignore = %v, want [examples]", cfg.Ignore)
+	}
+
+	if len(cfg.LockPlatforms) != 2 || cfg.LockPlatforms[1] != "darwin_arm64" {
+		t.Errorf("LockPlatforms = %v", cfg.LockPlatforms)
+	}
+
+	overrides := cfg.ProviderAllows()
+	if len(overrides) != 1 || overrides["hashicorp/aws"] != "patch" {
+		t.Errorf("ProviderAllows() = %v", overrides)
+	}
+}
+
+func TestLoadDefault_Missing(t *testing.T) {
+	cfg, err := LoadDefault(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadDefault failed: %v", err)
+	}
+	if cfg.Allow != "" || len(cfg.Providers) != 0 {
+		t.Errorf("Expected empty config, got %+v", cfg)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, DefaultFile)
+	if err := os.WriteFile(path, []byte(`allow = [`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected error for invalid config")
+	}
+}
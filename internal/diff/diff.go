@@ -0,0 +1,191 @@
+// Package diff renders line based unified diffs.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change.
+const DefaultContext = 3
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type op struct {
+	kind opKind
+	line string
+}
+
+// Unified returns the unified diff turning a into b, labelled with the given
+// file names. It returns an empty string when the inputs are identical.
+func Unified(oldName, newName string, a, b []byte, context int) string {
+	if string(a) == string(b) {
+		return ""
+	}
+
+	ops := lineOps(splitLines(string(a)), splitLines(string(b)))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks(ops, context) {
+		sb.WriteString(h)
+	}
+	return sb.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineOps computes an edit script from the longest common subsequence of
+// the two line slices. Configuration files are small, so the quadratic
+// table is not a concern.
+func lineOps(a, b []string) []op {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []op
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, op{opEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{opDelete, a[i]})
+			i++
+		default:
+			ops = append(ops, op{opInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, op{opDelete, a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, op{opInsert, b[j]})
+	}
+	return ops
+}
+
+// hunks groups the edit script into hunks with the given amount of context.
+func hunks(ops []op, context int) []string {
+	var out []string
+
+	for start := 0; start < len(ops); {
+		// Find the next change.
+		for start < len(ops) && ops[start].kind == opEqual {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		first := start - context
+		if first < 0 {
+			first = 0
+		}
+
+		// Extend the hunk while changes are within 2*context lines of each
+		// other.
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == opEqual {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				break
+			}
+			end = run
+		}
+
+		last := end + context
+		if last > len(ops) {
+			last = len(ops)
+		}
+
+		out = append(out, renderHunk(ops, first, last))
+		start = last
+	}
+
+	return out
+}
+
+func renderHunk(ops []op, first, last int) string {
+	// Line numbers are 1-based positions in the old and new files.
+	oldStart, newStart := 1, 1
+	for _, o := range ops[:first] {
+		if o.kind != opInsert {
+			oldStart++
+		}
+		if o.kind != opDelete {
+			newStart++
+		}
+	}
+
+	var body strings.Builder
+	oldLines, newLines := 0, 0
+	for _, o := range ops[first:last] {
+		line := o.line
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n\\ No newline at end of file\n"
+		}
+		switch o.kind {
+		case opEqual:
+			body.WriteString(" " + line)
+			oldLines++
+			newLines++
+		case opDelete:
+			body.WriteString("-" + line)
+			oldLines++
+		case opInsert:
+			body.WriteString("+" + line)
+			newLines++
+		}
+	}
+
+	return fmt.Sprintf("@@ -%s +%s @@\n%s", hunkRange(oldStart, oldLines), hunkRange(newStart, newLines), body.String())
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
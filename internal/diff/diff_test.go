@@ -0,0 +1,81 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	a := `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "4.0.0"
+    }
+  }
+}
+`
+	b := `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "5.0.0"
+    }
+  }
+}
+`
+	want := `--- a/provider.tf
++++ b/provider.tf
+@@ -2,7 +2,7 @@
+   required_providers {
+     aws = {
+       source  = "hashicorp/aws"
+-      version = "4.0.0"
++      version = "5.0.0"
+     }
+   }
+ }
+`
+	got := Unified("a/provider.tf", "b/provider.tf", []byte(a), []byte(b), DefaultContext)
+	if got != want {
+		t.Errorf("Unified() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnified_Identical(t *testing.T) {
+	if got := Unified("a", "b", []byte("x\n"), []byte("x\n"), DefaultContext); got != "" {
+		t.Errorf("Unified() = %q, want empty", got)
+	}
+}
+
+func TestUnified_SeparateHunks(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	b := "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n"
+	want := `--- a
++++ b
+@@ -1,2 +1,2 @@
+-1
++one
+ 2
+@@ -9,2 +9,2 @@
+ 9
+-10
++ten
+`
+	got := Unified("a", "b", []byte(a), []byte(b), 1)
+	if got != want {
+		t.Errorf("Unified() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnified_NoTrailingNewline(t *testing.T) {
+	want := `--- a
++++ b
+@@ -1 +1 @@
+-old
+\ No newline at end of file
++new
+\ No newline at end of file
+`
+	got := Unified("a", "b", []byte("old"), []byte("new"), DefaultContext)
+	if got != want {
+		t.Errorf("Unified() =\n%s\nwant:\n%s", got, want)
+	}
+}
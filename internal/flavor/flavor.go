@@ -0,0 +1,52 @@
+// Package flavor describes the Terraform and OpenTofu dialects tfinit
+// generates and updates configurations for.
+package flavor
+
+import "fmt"
+
+// Flavor is the tool a configuration is written for. The zero value is
+// Terraform.
+type Flavor string
+
+const (
+	Terraform Flavor = "terraform"
+	OpenTofu  Flavor = "tofu"
+)
+
+// Parse parses a flavor name; "" is Terraform and "opentofu" is accepted
+// for OpenTofu.
+func Parse(s string) (Flavor, error) {
+	switch s {
+	case "", string(Terraform):
+		return Terraform, nil
+	case string(OpenTofu), "opentofu":
+		return OpenTofu, nil
+	}
+	return "", fmt.Errorf("unknown flavor %q (available: terraform, tofu)", s)
+}
+
+// IsOpenTofu reports whether f is OpenTofu.
+func (f Flavor) IsOpenTofu() bool {
+	return f == OpenTofu
+}
+
+// RegistryHost is the hostname implied by provider sources without one.
+func (f Flavor) RegistryHost() string {
+	if f.IsOpenTofu() {
+		return "registry.opentofu.org"
+	}
+	return "registry.terraform.io"
+}
+
+// RegistryURL is the base URL of the provider registry API.
+func (f Flavor) RegistryURL() string {
+	return "https://" + f.RegistryHost() + "/v1/providers"
+}
+
+// Command is the name of the CLI, as used in hints printed to the user.
+func (f Flavor) Command() string {
+	if f.IsOpenTofu() {
+		return "tofu"
+	}
+	return "terraform"
+}
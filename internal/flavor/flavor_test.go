@@ -0,0 +1,30 @@
+package flavor
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := map[string]Flavor{
+		"":          Terraform,
+		"terraform": Terraform,
+		"tofu":      OpenTofu,
+		"opentofu":  OpenTofu,
+	}
+	for in, want := range tests {
+		if got, err := Parse(in); err != nil || got != want {
+			t.Errorf("Parse(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := Parse("pulumi"); err == nil {
+		t.Error("Parse(pulumi) error = nil")
+	}
+}
+
+func TestFlavor(t *testing.T) {
+	var zero Flavor
+	if zero.RegistryHost() != "registry.terraform.io" || zero.Command() != "terraform" {
+		t.Errorf("zero Flavor = %s, %s, want Terraform", zero.RegistryHost(), zero.Command())
+	}
+	if OpenTofu.RegistryURL() != "https://registry.opentofu.org/v1/providers" || OpenTofu.Command() != "tofu" {
+		t.Errorf("OpenTofu = %s, %s", OpenTofu.RegistryURL(), OpenTofu.Command())
+	}
+}
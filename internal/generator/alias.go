@@ -0,0 +1,204 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"warike/base/internal/catalog"
+)
+
+// Alias is an additional configuration of a provider, such as AWS in
+// us-east-1 for CloudFront certificates or in a security account, used by
+// resources through provider = <provider>.<alias>.
+type Alias struct {
+	Name string
+	// Settings maps alias settings of the provider definition, such as
+	// region or role_arn, to their values.
+	Settings map[string]string
+}
+
+// ParseAlias parses an alias given as provider.alias[,setting=value...],
+// e.g. aws.security,region=us-east-1,role_arn=arn:aws:iam::111111111111:role/terraform.
+func ParseAlias(s string) (provider string, a Alias, err error) {
+	parts := strings.Split(s, ",")
+	provider, a.Name, _ = strings.Cut(parts[0], ".")
+	if provider == "" || a.Name == "" {
+		return "", Alias{}, fmt.Errorf("invalid alias %q, want provider.alias[,setting=value...]", s)
+	}
+	for _, kv := range parts[1:] {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return "", Alias{}, fmt.Errorf("invalid alias setting %q, want setting=value", kv)
+		}
+		if a.Settings == nil {
+			a.Settings = make(map[string]string)
+		}
+		a.Settings[key] = value
+	}
+	return provider, a, nil
+}
+
+// Check reports an alias name that is not an identifier and settings that
+// the provider definition does not offer.
+func (a Alias) Check(def catalog.Provider) error {
+	if !hclsyntax.ValidIdentifier(a.Name) {
+		return fmt.Errorf("provider %s: invalid alias name %q", def.Name, a.Name)
+	}
+	for name, value := range a.Settings {
+		if _, ok := def.AliasSetting(name); !ok {
+			var available []string
+			for _, s := range def.AliasSettings {
+				available = append(available, s.Name)
+			}
+			return fmt.Errorf("provider %s: alias %s: unknown setting %q (available: %s)", def.Name, a.Name, name, strings.Join(available, ", "))
+		}
+		if value == "" {
+			return fmt.Errorf("provider %s: alias %s: empty %s", def.Name, a.Name, name)
+		}
+	}
+	return nil
+}
+
+// String formats a like ParseAlias reads it, without the provider.
+func (a Alias) String() string {
+	parts := []string{a.Name}
+	for name, value := range a.Settings {
+		parts = append(parts, name+"="+value)
+	}
+	sort.Strings(parts[1:])
+	return strings.Join(parts, ",")
+}
+
+// checkAliases checks the aliases of p against its definition.
+func (p ProviderConfig) checkAliases() error {
+	seen := make(map[string]bool)
+	for _, a := range p.Aliases {
+		if seen[a.Name] {
+			return fmt.Errorf("provider %s: duplicate alias %q", p.Name, a.Name)
+		}
+		seen[a.Name] = true
+		if err := a.Check(p.Definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aliasVariable names the variable, and the local, holding a setting of an
+// alias of p.
+func (p ProviderConfig) aliasVariable(a Alias, setting string) string {
+	return p.Name + "_" + a.Name + "_" + setting
+}
+
+// withAliasDefinitions returns the definition of p with a local, a
+// variable and a tfvars entry for every setting of its aliases. Entries
+// already present are kept, so that it can be applied again.
+func (p ProviderConfig) withAliasDefinitions() catalog.Provider {
+	def := p.Definition
+	if len(p.Aliases) == 0 {
+		return def
+	}
+	def.Locals = append([]catalog.Local(nil), def.Locals...)
+	def.Variables = append([]catalog.Variable(nil), def.Variables...)
+
+	for _, a := range p.Aliases {
+		for _, s := range def.AliasSettings {
+			value, ok := a.Settings[s.Name]
+			if !ok {
+				continue
+			}
+			name := p.aliasVariable(a, s.Name)
+			if _, ok := findLocal(def.Locals, name); ok {
+				continue
+			}
+			description := s.Description
+			if description == "" {
+				description = s.Name
+			}
+			def.Locals = append(def.Locals, catalog.Local{Name: name, Value: "var." + name})
+			def.Variables = append(def.Variables, catalog.Variable{
+				Name:        name,
+				Description: fmt.Sprintf("%s (%s alias)", description, a.Name),
+				Type:        "string",
+				Tfvars:      cty.StringVal(value),
+			})
+		}
+	}
+	return def
+}
+
+func findLocal(locals []catalog.Local, name string) (catalog.Local, bool) {
+	for _, l := range locals {
+		if l.Name == name {
+			return l, true
+		}
+	}
+	return catalog.Local{}, false
+}
+
+// AliasConfig returns the body of the provider block of alias a: the alias
+// argument, then the configuration of the default provider block with the
+// settings of a in place of the arguments they set.
+func (p ProviderConfig) AliasConfig(a Alias) (string, error) {
+	file, diags := hclwrite.ParseConfig([]byte(p.Definition.Config+"\n"), p.Name, hcl.InitialPos)
+	if diags.HasErrors() {
+		return "", fmt.Errorf("provider %s: config: %w", p.Name, diags)
+	}
+
+	for _, s := range p.Definition.AliasSettings {
+		if _, ok := a.Settings[s.Name]; !ok {
+			continue
+		}
+		path := s.Path()
+		body := file.Body()
+		for _, name := range path[:len(path)-1] {
+			block := body.FirstMatchingBlock(name, nil)
+			if block == nil {
+				block = body.AppendNewBlock(name, nil)
+			}
+			body = block.Body()
+		}
+		body.SetAttributeTraversal(path[len(path)-1], hcl.Traversal{
+			hcl.TraverseRoot{Name: "local"},
+			hcl.TraverseAttr{Name: p.aliasVariable(a, s.Name)},
+		})
+	}
+
+	alias := hclwrite.NewEmptyFile()
+	alias.Body().SetAttributeValue("alias", cty.StringVal(a.Name))
+	return strings.TrimSpace(string(alias.Bytes()) + string(file.Bytes())), nil
+}
+
+// AliasAddresses returns the addresses of the aliased configurations of p,
+// such as aws.security, for configuration_aliases.
+func (p ProviderConfig) AliasAddresses() []string {
+	var addrs []string
+	for _, a := range p.Aliases {
+		addrs = append(addrs, p.Name+"."+a.Name)
+	}
+	return addrs
+}
+
+// ModuleProviders returns the provider configurations that the root module
+// passes on to the shared module of the dirs layout, or nil when the
+// default configurations are enough. Passing aliases means listing every
+// configuration, since the module then inherits none implicitly.
+func (d GeneratorData) ModuleProviders() []string {
+	var addrs []string
+	aliased := false
+	for _, p := range d.Providers {
+		addrs = append(addrs, p.Name)
+		addrs = append(addrs, p.AliasAddresses()...)
+		aliased = aliased || len(p.Aliases) > 0
+	}
+	if !aliased {
+		return nil
+	}
+	return addrs
+}
@@ -0,0 +1,116 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAlias(t *testing.T) {
+	provider, a, err := ParseAlias("aws.security,region=us-east-1,role_arn=arn:aws:iam::111111111111:role/terraform")
+	if err != nil {
+		t.Fatalf("ParseAlias() error = %v", err)
+	}
+	if provider != "aws" || a.Name != "security" || a.Settings["region"] != "us-east-1" || a.Settings["role_arn"] != "arn:aws:iam::111111111111:role/terraform" {
+		t.Errorf("ParseAlias() = %q, %+v", provider, a)
+	}
+	if got := a.String(); got != "security,region=us-east-1,role_arn=arn:aws:iam::111111111111:role/terraform" {
+		t.Errorf("String() = %q", got)
+	}
+
+	for _, s := range []string{"aws", "aws.", ".security", "aws.security,region"} {
+		if _, _, err := ParseAlias(s); err == nil {
+			t.Errorf("ParseAlias(%q) error = nil", s)
+		}
+	}
+}
+
+func aliasData(t *testing.T, specs ...string) GeneratorData {
+	t.Helper()
+	data := testData()
+	for _, spec := range specs {
+		_, a, err := ParseAlias(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data.Providers[0].Aliases = append(data.Providers[0].Aliases, a)
+	}
+	return data
+}
+
+func TestWriteProject_Aliases(t *testing.T) {
+	data := aliasData(t, "aws.security,region=us-east-1,role_arn=arn:aws:iam::111111111111:role/terraform", "aws.us_east_1,region=us-east-1")
+	data.Layout = LayoutDirs
+	data.Environments = []string{"prod"}
+
+	dir := t.TempDir()
+	if err := WriteProject(dir, data); err != nil {
+		t.Fatalf("WriteProject() error = %v", err)
+	}
+
+	checks := map[string][]string{
+		"envs/prod/provider.tf": {
+			"provider \"aws\" {\n  alias   = \"security\"\n  region  = local.aws_security_region\n  profile = local.aws_profile\n",
+			"  assume_role {\n    role_arn = local.aws_security_role_arn\n  }\n}",
+			"provider \"aws\" {\n  alias   = \"us_east_1\"\n  region  = local.aws_us_east_1_region\n",
+			"aws_security_role_arn = var.aws_security_role_arn",
+		},
+		"envs/prod/variables.tf":     {"variable \"aws_us_east_1_region\" {\n  description = \"AWS region (us_east_1 alias)\"\n  type        = string\n}"},
+		"envs/prod/terraform.tfvars": {`aws_security_role_arn = "arn:aws:iam::111111111111:role/terraform"`},
+		"envs/prod/main.tf":          {"  providers = {\n    aws           = aws\n    aws.security  = aws.security\n    aws.us_east_1 = aws.us_east_1\n  }"},
+		"modules/main/versions.tf":   {"configuration_aliases = [aws.security, aws.us_east_1]"},
+	}
+	for name, want := range checks {
+		content := readFile(t, dir, name)
+		for _, s := range want {
+			if !strings.Contains(content, s) {
+				t.Errorf("%s = %s\nwant it to contain %s", name, content, s)
+			}
+		}
+	}
+}
+
+func TestWriteProject_NoAliases(t *testing.T) {
+	data := testData()
+	data.Layout = LayoutDirs
+	data.Environments = []string{"prod"}
+
+	dir := t.TempDir()
+	if err := WriteProject(dir, data); err != nil {
+		t.Fatalf("WriteProject() error = %v", err)
+	}
+	if got := readFile(t, dir, "envs/prod/main.tf"); strings.Contains(got, "providers") {
+		t.Errorf("main.tf = %s\nwant no providers map without aliases", got)
+	}
+}
+
+func TestValidate_Aliases(t *testing.T) {
+	tests := map[string][]string{
+		"unknown setting": {"aws.west,zone=a"},
+		"invalid name":    {"aws.us east,region=us-east-1"},
+		"duplicate":       {"aws.west,region=us-west-1", "aws.west,region=us-west-2"},
+		"empty value":     {"aws.west,region="},
+	}
+	for name, specs := range tests {
+		if err := aliasData(t, specs...).Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil", name)
+		}
+	}
+}
+
+func TestRemoveProvider_Aliases(t *testing.T) {
+	data := aliasData(t, "aws.security,role_arn=arn:aws:iam::111111111111:role/terraform")
+	data.Providers = append(data.Providers, cloudflare)
+
+	dir := t.TempDir()
+	if err := WriteProject(dir, data); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RemoveProvider(dir, ProviderConfig{Name: "aws"}); err != nil {
+		t.Fatalf("RemoveProvider() error = %v", err)
+	}
+	for name, content := range snapshot(t, dir) {
+		if strings.Contains(content, "aws") {
+			t.Errorf("%s still mentions aws:\n%s", name, content)
+		}
+	}
+}
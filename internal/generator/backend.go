@@ -0,0 +1,179 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// BackendTypes lists the supported state backends, in the order they are
+// offered.
+var BackendTypes = []string{"s3", "gcs", "azurerm", "cloud", "http", "local"}
+
+// S3 state locking modes.
+const (
+	LockingLockfile = "lockfile"
+	LockingDynamoDB = "dynamodb"
+)
+
+// DefaultEnvironments is used when no environments are given.
+var DefaultEnvironments = []string{"dev"}
+
+// Backend describes the remote state backend of a generated project. The
+// backend block in backend.tf is left empty and its settings are written to
+// a partial configuration file per environment, envs/<env>.backend.hcl (or
+// backend.hcl per root module with the dirs and workspaces layouts), to be
+// passed to terraform init -backend-config. The cloud block does not
+// support partial configuration and is written in full instead.
+type Backend struct {
+	Type string
+
+	// Locking selects s3 state locking: LockingLockfile (S3-native, the
+	// default) or LockingDynamoDB.
+	Locking string
+
+	// Config overrides the generated settings by name, e.g. bucket or
+	// organization. Values are written as strings unless they are a bool or
+	// a number.
+	Config map[string]string
+}
+
+// ParseBackend parses a backend name as offered by the create command: one
+// of BackendTypes, with s3 optionally suffixed by its locking mode
+// (s3-dynamodb, s3-lockfile). "" and "none" return nil.
+func ParseBackend(s string) (*Backend, error) {
+	switch s {
+	case "", "none":
+		return nil, nil
+	case "s3-" + LockingDynamoDB:
+		return &Backend{Type: "s3", Locking: LockingDynamoDB}, nil
+	case "s3-" + LockingLockfile:
+		return &Backend{Type: "s3", Locking: LockingLockfile}, nil
+	}
+	for _, t := range BackendTypes {
+		if s == t {
+			return &Backend{Type: t}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown backend %q (available: none, s3, s3-dynamodb, %s)", s, strings.Join(BackendTypes[1:], ", "))
+}
+
+// Setting is a backend attribute. Value is an HCL expression.
+type Setting struct {
+	Name  string
+	Value string
+}
+
+// Settings returns the backend attributes for the environment env of
+// project. An empty env, as with workspaces, leaves the environment out of
+// state paths. For the cloud backend env is ignored.
+func (b *Backend) Settings(project, env string) []Setting {
+	var s []Setting
+	add := func(name string, value interface{}) {
+		s = append(s, Setting{Name: name, Value: hclValue(value)})
+	}
+
+	switch b.Type {
+	case "s3":
+		add("bucket", project+"-tfstate")
+		add("key", path.Join(project, env, "terraform.tfstate"))
+		add("region", "us-east-1")
+		add("encrypt", true)
+		if b.Locking == LockingDynamoDB {
+			add("dynamodb_table", project+"-tflock")
+		} else {
+			add("use_lockfile", true)
+		}
+	case "gcs":
+		add("bucket", project+"-tfstate")
+		add("prefix", path.Join(project, env))
+	case "azurerm":
+		add("resource_group_name", project+"-tfstate")
+		add("storage_account_name", storageAccountName(project))
+		add("container_name", "tfstate")
+		add("key", path.Join(project, env)+".tfstate")
+	case "cloud":
+		add("organization", "my-organization")
+	case "http":
+		address := "https://state.example.com/" + path.Join(project, env)
+		add("address", address)
+		add("lock_address", address+"/lock")
+		add("unlock_address", address+"/lock")
+	case "local":
+		name := "terraform.tfstate"
+		if env != "" {
+			name = "terraform." + env + ".tfstate"
+		}
+		add("path", name)
+	}
+
+	return b.override(s)
+}
+
+// override replaces or appends the settings of b.Config.
+func (b *Backend) override(s []Setting) []Setting {
+	names := make([]string, 0, len(b.Config))
+	for name := range b.Config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := hclValue(parseScalar(b.Config[name]))
+		replaced := false
+		for i := range s {
+			if s[i].Name == name {
+				s[i].Value = value
+				replaced = true
+			}
+		}
+		if !replaced {
+			s = append(s, Setting{Name: name, Value: value})
+		}
+	}
+	return s
+}
+
+func parseScalar(v string) interface{} {
+	if v == "true" || v == "false" {
+		return v == "true"
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return n
+	}
+	return v
+}
+
+func hclValue(v interface{}) string {
+	var val cty.Value
+	switch v := v.(type) {
+	case bool:
+		val = cty.BoolVal(v)
+	case int64:
+		val = cty.NumberIntVal(v)
+	default:
+		val = cty.StringVal(fmt.Sprint(v))
+	}
+	return string(hclwrite.TokensForValue(val).Bytes())
+}
+
+// storageAccountName derives an Azure storage account name, which must be
+// 3 to 24 lowercase letters and digits.
+func storageAccountName(project string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(project) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		}
+	}
+	name := sb.String()
+	if len(name) > 17 {
+		name = name[:17]
+	}
+	return name + "tfstate"
+}
@@ -0,0 +1,111 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseBackend(t *testing.T) {
+	for _, name := range []string{"", "none"} {
+		if b, err := ParseBackend(name); err != nil || b != nil {
+			t.Errorf("ParseBackend(%q) = %v, %v, want nil", name, b, err)
+		}
+	}
+
+	b, err := ParseBackend("s3-dynamodb")
+	if err != nil || b.Type != "s3" || b.Locking != LockingDynamoDB {
+		t.Errorf("ParseBackend(s3-dynamodb) = %+v, %v", b, err)
+	}
+
+	if _, err := ParseBackend("consul"); err == nil {
+		t.Error("ParseBackend(consul) error = nil")
+	}
+}
+
+func TestWriteProject_Backend(t *testing.T) {
+	data := testData()
+	data.Backend = &Backend{Type: "s3", Config: map[string]string{"region": "eu-west-1", "kms_key_id": "alias/state"}}
+	data.Environments = []string{"dev", "prod"}
+
+	dir := t.TempDir()
+	if err := WriteProject(dir, data); err != nil {
+		t.Fatalf("WriteProject() error = %v", err)
+	}
+
+	backend, err := os.ReadFile(filepath.Join(dir, "backend.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(backend), `backend "s3" {}`) {
+		t.Errorf("backend.tf = %s", backend)
+	}
+
+	prod, err := os.ReadFile(filepath.Join(dir, "envs", "prod.backend.hcl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `bucket       = "demo-tfstate"
+key          = "demo/prod/terraform.tfstate"
+region       = "eu-west-1"
+encrypt      = true
+use_lockfile = true
+kms_key_id   = "alias/state"
+`
+	if string(prod) != want {
+		t.Errorf("prod.backend.hcl =\n%s\nwant\n%s", prod, want)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "envs", "dev.backend.hcl")); err != nil {
+		t.Errorf("dev.backend.hcl missing: %v", err)
+	}
+}
+
+func TestWriteProject_BackendSettings(t *testing.T) {
+	tests := []struct {
+		backend Backend
+		file    string
+		want    []string
+	}{
+		{Backend{Type: "s3", Locking: LockingDynamoDB}, "envs/dev.backend.hcl", []string{`dynamodb_table = "demo-tflock"`}},
+		{Backend{Type: "gcs"}, "envs/dev.backend.hcl", []string{`prefix = "demo/dev"`}},
+		{Backend{Type: "azurerm"}, "envs/dev.backend.hcl", []string{`storage_account_name = "demotfstate"`, `key                  = "demo/dev.tfstate"`}},
+		{Backend{Type: "http"}, "envs/dev.backend.hcl", []string{`lock_address   = "https://state.example.com/demo/dev/lock"`}},
+		{Backend{Type: "local"}, "envs/dev.backend.hcl", []string{`path = "terraform.dev.tfstate"`}},
+		{Backend{Type: "cloud", Config: map[string]string{"organization": "acme"}}, "backend.tf", []string{`organization = "acme"`, `tags = ["demo"]`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.backend.Type, func(t *testing.T) {
+			data := testData()
+			backend := tt.backend
+			data.Backend = &backend
+
+			dir := t.TempDir()
+			if err := WriteProject(dir, data); err != nil {
+				t.Fatalf("WriteProject() error = %v", err)
+			}
+			got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(tt.file)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(string(got), s) {
+					t.Errorf("%s = %s\nwant it to contain %s", tt.file, got, s)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteProject_NoBackend(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteProject(dir, testData()); err != nil {
+		t.Fatalf("WriteProject() error = %v", err)
+	}
+	for _, name := range []string{"backend.tf", "envs"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should not be generated without a backend, stat error = %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,781 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// AddProvider wires p into the existing project in dir the way WriteProject
+// would have: its required_providers entry, provider block, locals,
+// variables and tfvars entries. p.Definition is looked up in the embedded
+// catalog when empty; locals, variables and tfvars entries that already
+// exist are kept. With the dirs layout every envs/<env>/ root module gets
+// the provider and modules/main its required_providers entry. The rest of
+// each file is left byte for byte; AddProvider returns the files it changed.
+func AddProvider(dir string, p ProviderConfig) ([]string, error) {
+	p = GeneratorData{Providers: []ProviderConfig{p}}.withDefinitions().Providers[0]
+
+	roots, shared, err := projectModules(dir)
+	if err != nil {
+		return nil, err
+	}
+	s, err := renderSnippets(p)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range roots {
+		if m.requirement(p.Name) != nil {
+			return nil, fmt.Errorf("provider %q is already in %s", p.Name, m.dir)
+		}
+		if err := m.addProvider(p, s); err != nil {
+			return nil, err
+		}
+	}
+	if shared != nil && shared.requirement(p.Name) == nil {
+		if err := shared.addRequirement(p.Name, s.sharedRequirement); err != nil {
+			return nil, err
+		}
+	}
+
+	return writeModules(append(roots, shared))
+}
+
+// RemoveProvider deletes what AddProvider adds for p from the project in
+// dir, along with its aliased provider blocks and their inputs. It refuses
+// while resources, data sources or module calls use the provider, or other
+// configuration references its locals or variables.
+func RemoveProvider(dir string, p ProviderConfig) ([]string, error) {
+	p = GeneratorData{Providers: []ProviderConfig{p}}.withDefinitions().Providers[0]
+
+	roots, shared, err := projectModules(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	locals := make(map[string]bool)
+	for _, l := range p.Definition.Locals {
+		locals[l.Name] = true
+	}
+	vars := make(map[string]bool)
+	for _, v := range p.Definition.Variables {
+		vars[v.Name] = true
+	}
+	for _, m := range roots {
+		m.aliasInputs(p.Name, locals, vars)
+	}
+
+	var users []string
+	for _, m := range append(roots, shared) {
+		if m != nil {
+			users = append(users, m.providerUsers(p.Name)...)
+		}
+	}
+	for _, m := range roots {
+		if m.requirement(p.Name) == nil {
+			return nil, fmt.Errorf("provider %q is not in %s", p.Name, m.dir)
+		}
+		users = append(users, m.references(p.Name, locals, vars)...)
+	}
+	if len(users) > 0 {
+		return nil, fmt.Errorf("provider %q is still used by %s", p.Name, strings.Join(users, ", "))
+	}
+
+	for _, m := range roots {
+		m.removeProvider(p.Name, locals, vars)
+	}
+	if shared != nil {
+		if attr := shared.requirement(p.Name); attr != nil {
+			shared.deleteRange(attr.file, attr.attr.SrcRange)
+		}
+	}
+
+	return writeModules(append(roots, shared))
+}
+
+// snippets are the pieces of a provider as the built-in templates render
+// them, each a run of whole lines.
+type snippets struct {
+	requirement       string
+	sharedRequirement string
+	blocks            string
+	locals            []namedSnippet
+	variables         []namedSnippet
+	tfvars            []namedSnippet
+}
+
+type namedSnippet struct {
+	name, text string
+}
+
+// renderSnippets renders a project of p alone and cuts its pieces out.
+func renderSnippets(p ProviderConfig) (snippets, error) {
+	var s snippets
+	data := GeneratorData{ProjectName: "project", Providers: []ProviderConfig{p}}
+
+	provider, err := renderBody(generateBuiltin("provider.tf", data))
+	if err != nil {
+		return s, err
+	}
+	if attr := provider.requirement(p.Name); attr != nil {
+		s.requirement = provider.lines(attr.attr.SrcRange)
+	}
+	for _, b := range provider.body.Blocks {
+		switch {
+		case b.Type == "provider" && firstLabel(b) == p.Name:
+			s.blocks += provider.lines(b.Range())
+		case b.Type == "locals":
+			for _, l := range p.Definition.Locals {
+				if attr, ok := b.Body.Attributes[l.Name]; ok {
+					s.locals = append(s.locals, namedSnippet{l.Name, provider.lines(attr.SrcRange)})
+				}
+			}
+		}
+	}
+
+	dirs := data
+	dirs.Layout = LayoutDirs
+	versions, err := renderBody(generateBuiltin("modules/main/versions.tf", dirs))
+	if err != nil {
+		return s, err
+	}
+	if attr := versions.requirement(p.Name); attr != nil {
+		s.sharedRequirement = versions.lines(attr.attr.SrcRange)
+	}
+
+	variables, err := renderBody(generateBuiltin("variables.tf", data))
+	if err != nil {
+		return s, err
+	}
+	tfvars, err := renderBody(generateBuiltin("terraform.tfvars", data))
+	if err != nil {
+		return s, err
+	}
+	for _, v := range p.Definition.Variables {
+		for _, b := range variables.body.Blocks {
+			if b.Type == "variable" && firstLabel(b) == v.Name {
+				s.variables = append(s.variables, namedSnippet{v.Name, variables.lines(b.Range())})
+			}
+		}
+		if attr, ok := tfvars.body.Attributes[v.Name]; ok {
+			s.tfvars = append(s.tfvars, namedSnippet{v.Name, tfvars.lines(attr.SrcRange)})
+		}
+	}
+	return s, nil
+}
+
+// renderBody parses the output of a built-in template.
+func renderBody(content []byte, err error) (*configFile, error) {
+	if err != nil {
+		return nil, err
+	}
+	return parseConfigFile("rendered", content)
+}
+
+// configFile is a parsed configuration or tfvars file and the edits
+// pending on it.
+type configFile struct {
+	path  string
+	src   []byte
+	body  *hclsyntax.Body
+	edits []textEdit
+}
+
+// textEdit replaces src[start:end] with text.
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+func parseConfigFile(path string, src []byte) (*configFile, error) {
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("%s: unexpected body type %T", path, file.Body)
+	}
+	return &configFile{path: path, src: src, body: body}, nil
+}
+
+// lineStart and lineEnd widen a byte offset to the start of its line and
+// past the end of its line.
+func (f *configFile) lineStart(offset int) int {
+	return bytes.LastIndexByte(f.src[:offset], '\n') + 1
+}
+
+func (f *configFile) lineEnd(offset int) int {
+	if i := bytes.IndexByte(f.src[offset:], '\n'); i >= 0 {
+		return offset + i + 1
+	}
+	return len(f.src)
+}
+
+// lines returns the whole lines covered by r.
+func (f *configFile) lines(r hcl.Range) string {
+	return string(f.src[f.lineStart(r.Start.Byte):f.lineEnd(r.End.Byte)])
+}
+
+// insert adds text at offset, which must be the start of a line.
+func (f *configFile) insert(offset int, text string) {
+	f.edits = append(f.edits, textEdit{start: offset, end: offset, text: text})
+}
+
+// apply returns the content of f with its edits applied. Deleted runs of
+// lines that leave two blank lines, or a blank line before a closing brace
+// or the end of the file, take the blank line before them along.
+func (f *configFile) apply() []byte {
+	edits := make([]textEdit, len(f.edits))
+	copy(edits, f.edits)
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+
+	// Merge adjacent deletions so runs of lines are judged as a whole.
+	var merged []textEdit
+	for _, e := range edits {
+		if n := len(merged); n > 0 && e.text == "" && merged[n-1].text == "" && e.start <= merged[n-1].end {
+			merged[n-1].end = max(merged[n-1].end, e.end)
+			continue
+		}
+		merged = append(merged, e)
+	}
+
+	for i, e := range merged {
+		if e.text != "" || e.start == e.end || e.start == 0 {
+			continue
+		}
+		prev := f.lineStart(e.start - 1)
+		if !isBlank(f.src[prev:e.start]) {
+			continue
+		}
+		next := f.src[e.end:f.lineEnd(e.end)]
+		if e.end == len(f.src) || isBlank(next) || strings.TrimSpace(string(next)) == "}" {
+			merged[i].start = prev
+		}
+	}
+
+	var out []byte
+	last := 0
+	for _, e := range merged {
+		if e.start < last {
+			continue
+		}
+		out = append(out, f.src[last:e.start]...)
+		out = append(out, e.text...)
+		last = e.end
+	}
+	return append(out, f.src[last:]...)
+}
+
+func isBlank(line []byte) bool {
+	return len(bytes.TrimSpace(line)) == 0
+}
+
+// moduleConfig is the configuration of a module directory together with
+// the tfvars files that set its variables.
+type moduleConfig struct {
+	dir    string
+	files  []*configFile
+	tfvars []*configFile
+	// ext is the configuration file extension new files are written with.
+	ext string
+}
+
+// projectModules loads the root modules of the project in dir. With the
+// dirs layout these are envs/<env>/ and shared is modules/main; otherwise
+// dir is the only root module and shared is nil.
+func projectModules(dir string) (roots []*moduleConfig, shared *moduleConfig, err error) {
+	envs, err := filepath.Glob(filepath.Join(dir, "envs", "*"))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, env := range envs {
+		if info, err := os.Stat(env); err != nil || !info.IsDir() {
+			continue
+		}
+		m, err := loadModuleConfig(env, filepath.Join(env, "*.tfvars"))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(m.files) > 0 {
+			roots = append(roots, m)
+		}
+	}
+
+	if len(roots) > 0 {
+		if shared, err = loadModuleConfig(filepath.Join(dir, "modules", "main")); err != nil {
+			return nil, nil, err
+		}
+		if len(shared.files) == 0 {
+			shared = nil
+		}
+		return roots, shared, nil
+	}
+
+	m, err := loadModuleConfig(dir, filepath.Join(dir, "*.tfvars"), filepath.Join(dir, "envs", "*.tfvars"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(m.files) == 0 {
+		return nil, nil, fmt.Errorf("no .tf files found in %s", dir)
+	}
+	return []*moduleConfig{m}, nil, nil
+}
+
+// loadModuleConfig parses the .tf and .tofu files of dir and the tfvars
+// files matching the globs.
+func loadModuleConfig(dir string, tfvarsGlobs ...string) (*moduleConfig, error) {
+	m := &moduleConfig{dir: dir, ext: ".tf"}
+
+	load := func(glob string) ([]*configFile, error) {
+		paths, err := filepath.Glob(glob)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(paths)
+		var files []*configFile
+		for _, path := range paths {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			f, err := parseConfigFile(path, src)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, f)
+		}
+		return files, nil
+	}
+
+	for _, ext := range []string{".tf", ".tofu"} {
+		files, err := load(filepath.Join(dir, "*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		if len(files) > 0 && len(m.files) == 0 {
+			m.ext = ext
+		}
+		m.files = append(m.files, files...)
+	}
+	for _, glob := range tfvarsGlobs {
+		files, err := load(glob)
+		if err != nil {
+			return nil, err
+		}
+		m.tfvars = append(m.tfvars, files...)
+	}
+	return m, nil
+}
+
+// blockRef is a block of one of the files of a module.
+type blockRef struct {
+	file  *configFile
+	block *hclsyntax.Block
+}
+
+// attrRef is an attribute of one of the files of a module.
+type attrRef struct {
+	file *configFile
+	attr *hclsyntax.Attribute
+}
+
+// requiredProviders returns the first terraform { required_providers }
+// block of the module.
+func (m *moduleConfig) requiredProviders() *blockRef {
+	for _, f := range m.files {
+		if b := f.requiredProviders(); b != nil {
+			return &blockRef{f, b}
+		}
+	}
+	return nil
+}
+
+func (f *configFile) requiredProviders() *hclsyntax.Block {
+	for _, b := range f.body.Blocks {
+		if b.Type != "terraform" {
+			continue
+		}
+		for _, inner := range b.Body.Blocks {
+			if inner.Type == "required_providers" {
+				return inner
+			}
+		}
+	}
+	return nil
+}
+
+// requirement returns the required_providers entry of name.
+func (m *moduleConfig) requirement(name string) *attrRef {
+	for _, f := range m.files {
+		if attr := f.requirement(name); attr != nil {
+			return attr
+		}
+	}
+	return nil
+}
+
+func (f *configFile) requirement(name string) *attrRef {
+	for _, b := range f.body.Blocks {
+		if b.Type != "terraform" {
+			continue
+		}
+		for _, inner := range b.Body.Blocks {
+			if attr, ok := inner.Body.Attributes[name]; ok && inner.Type == "required_providers" {
+				return &attrRef{f, attr}
+			}
+		}
+	}
+	return nil
+}
+
+// blocks returns the top-level blocks of the given type, in file order.
+func (m *moduleConfig) blocks(typ string) []blockRef {
+	var found []blockRef
+	for _, f := range m.files {
+		for _, b := range f.body.Blocks {
+			if b.Type == typ {
+				found = append(found, blockRef{f, b})
+			}
+		}
+	}
+	return found
+}
+
+// addRequirement inserts a required_providers entry.
+func (m *moduleConfig) addRequirement(name, text string) error {
+	rp := m.requiredProviders()
+	if rp == nil {
+		return fmt.Errorf("no required_providers block found in %s", m.dir)
+	}
+	rp.file.insert(rp.file.lineStart(rp.block.CloseBraceRange.Start.Byte), text)
+	return nil
+}
+
+// addProvider inserts the pieces of p into a root module: its provider
+// blocks after the last provider block, its locals ahead of the tags local,
+// its variables after the last variable and its tfvars entries at the end
+// of every tfvars file.
+func (m *moduleConfig) addProvider(p ProviderConfig, s snippets) error {
+	if err := m.addRequirement(p.Name, s.requirement); err != nil {
+		return err
+	}
+
+	anchor := m.requiredProviders()
+	var after *hclsyntax.Block
+	for _, b := range m.blocks("terraform") {
+		if b.file == anchor.file && b.block.Range().ContainsOffset(anchor.block.Range().Start.Byte) {
+			after = b.block
+		}
+	}
+	if providers := m.blocks("provider"); len(providers) > 0 {
+		last := providers[len(providers)-1]
+		anchor.file, after = last.file, last.block
+	}
+	blocks := s.blocks
+
+	var locals []string
+	for _, l := range s.locals {
+		if !m.hasLocal(l.name) {
+			locals = append(locals, l.text)
+		}
+	}
+	if len(locals) > 0 {
+		text := strings.Join(locals, "")
+		if found := m.blocks("locals"); len(found) > 0 {
+			lb := found[0]
+			for _, b := range found {
+				if b.file == anchor.file {
+					lb = b
+					break
+				}
+			}
+			if tags, ok := lb.block.Body.Attributes["tags"]; ok {
+				lb.file.insert(lb.file.lineStart(tags.SrcRange.Start.Byte), text+"\n")
+			} else {
+				lb.file.insert(lb.file.lineStart(lb.block.CloseBraceRange.Start.Byte), "\n"+text)
+			}
+		} else {
+			blocks += "\nlocals {\n" + text + "}\n"
+		}
+	}
+	if blocks != "" {
+		anchor.file.insert(anchor.file.lineEnd(after.Range().End.Byte), "\n"+blocks)
+	}
+
+	var variables []string
+	for _, v := range s.variables {
+		if !m.hasVariable(v.name) {
+			variables = append(variables, v.text)
+		}
+	}
+	if len(variables) > 0 {
+		text := strings.Join(variables, "\n")
+		if found := m.blocks("variable"); len(found) > 0 {
+			last := found[len(found)-1]
+			last.file.insert(last.file.lineEnd(last.block.Range().End.Byte), "\n"+text)
+		} else {
+			f := &configFile{path: filepath.Join(m.dir, "variables"+m.ext)}
+			f.insert(0, text)
+			m.files = append(m.files, f)
+		}
+	}
+
+	for _, f := range m.tfvars {
+		var text string
+		for _, v := range s.tfvars {
+			if _, ok := f.body.Attributes[v.name]; !ok {
+				text += v.text
+			}
+		}
+		if text == "" {
+			continue
+		}
+		if len(f.src) > 0 && f.src[len(f.src)-1] != '\n' {
+			text = "\n" + text
+		}
+		if len(bytes.TrimSpace(f.src)) > 0 {
+			text = "\n" + text
+		}
+		f.insert(len(f.src), text)
+	}
+	return nil
+}
+
+func (m *moduleConfig) hasLocal(name string) bool {
+	for _, b := range m.blocks("locals") {
+		if _, ok := b.block.Body.Attributes[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *moduleConfig) hasVariable(name string) bool {
+	for _, b := range m.blocks("variable") {
+		if firstLabel(b.block) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// removeProvider deletes the required_providers entry and provider blocks
+// of name and the given locals, variables and tfvars entries.
+func (m *moduleConfig) removeProvider(name string, locals, vars map[string]bool) {
+	if attr := m.requirement(name); attr != nil {
+		m.deleteRange(attr.file, attr.attr.SrcRange)
+	}
+	for _, b := range m.blocks("provider") {
+		if firstLabel(b.block) == name {
+			m.deleteRange(b.file, b.block.Range())
+		}
+	}
+	for _, b := range m.blocks("locals") {
+		for _, attr := range b.block.Body.Attributes {
+			if locals[attr.Name] {
+				m.deleteRange(b.file, attr.SrcRange)
+			}
+		}
+	}
+	for _, b := range m.blocks("variable") {
+		if vars[firstLabel(b.block)] {
+			m.deleteRange(b.file, b.block.Range())
+		}
+	}
+	for _, f := range m.tfvars {
+		for _, attr := range f.body.Attributes {
+			if vars[attr.Name] {
+				m.deleteRange(f, attr.SrcRange)
+			}
+		}
+	}
+}
+
+// deleteRange deletes the whole lines covered by r from f.
+func (m *moduleConfig) deleteRange(f *configFile, r hcl.Range) {
+	f.edits = append(f.edits, textEdit{start: f.lineStart(r.Start.Byte), end: f.lineEnd(r.End.Byte)})
+}
+
+// providerUsers describes the resources, data sources and module calls of
+// the module that use the provider name, explicitly or through their type.
+func (m *moduleConfig) providerUsers(name string) []string {
+	var users []string
+	for _, f := range m.files {
+		for _, b := range f.body.Blocks {
+			if usesProvider(b, name) {
+				users = append(users, fmt.Sprintf("%s (%s:%d)", blockAddress(b), filepath.Base(f.path), b.DefRange().Start.Line))
+			}
+		}
+	}
+	return users
+}
+
+func usesProvider(b *hclsyntax.Block, name string) bool {
+	switch b.Type {
+	case "resource", "data", "ephemeral":
+		if len(b.Labels) != 2 {
+			return false
+		}
+		if attr, ok := b.Body.Attributes["provider"]; ok {
+			for _, t := range attr.Expr.Variables() {
+				if t.RootName() == name {
+					return true
+				}
+			}
+			return false
+		}
+		// Terraform infers the provider from the type's first word.
+		prefix, _, _ := strings.Cut(b.Labels[0], "_")
+		return prefix == name
+	case "module":
+		if attr, ok := b.Body.Attributes["providers"]; ok {
+			for _, t := range attr.Expr.Variables() {
+				if t.RootName() == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// firstLabel returns the first label of b, or "" for a block written
+// without one, which Terraform rejects but which must not stop the edit.
+func firstLabel(b *hclsyntax.Block) string {
+	if len(b.Labels) == 0 {
+		return ""
+	}
+	return b.Labels[0]
+}
+
+func blockAddress(b *hclsyntax.Block) string {
+	switch b.Type {
+	case "resource":
+		return strings.Join(b.Labels, ".")
+	case "module":
+		return "module." + firstLabel(b)
+	}
+	return b.Type + "." + strings.Join(b.Labels, ".")
+}
+
+// references describes the references to the given locals and variables
+// from configuration that removing the provider name leaves in place.
+func (m *moduleConfig) references(name string, locals, vars map[string]bool) []string {
+	var refs []string
+	var visit func(f *configFile, body *hclsyntax.Body, isLocals bool)
+	visit = func(f *configFile, body *hclsyntax.Body, isLocals bool) {
+		for _, attr := range body.Attributes {
+			if isLocals && locals[attr.Name] {
+				continue
+			}
+			for _, t := range attr.Expr.Variables() {
+				local, v := refName(t, "local"), refName(t, "var")
+				if locals[local] || vars[v] {
+					refs = append(refs, fmt.Sprintf("%s.%s (%s:%d)", t.RootName(), local+v, filepath.Base(f.path), t.SourceRange().Start.Line))
+				}
+			}
+		}
+		for _, b := range body.Blocks {
+			visit(f, b.Body, false)
+		}
+	}
+
+	for _, f := range m.files {
+		for _, b := range f.body.Blocks {
+			if b.Type == "provider" && firstLabel(b) == name || b.Type == "variable" && vars[firstLabel(b)] {
+				continue
+			}
+			visit(f, b.Body, b.Type == "locals")
+		}
+	}
+	return refs
+}
+
+// aliasInputs adds to locals and vars the inputs of the provider blocks of
+// name outside its definition, such as those of aliases: the locals named
+// after the provider that the blocks use, and the variables named after it
+// that these locals use.
+func (m *moduleConfig) aliasInputs(name string, locals, vars map[string]bool) {
+	prefix := name + "_"
+	for _, b := range m.blocks("provider") {
+		if firstLabel(b.block) != name {
+			continue
+		}
+		for _, l := range bodyRefs(b.block.Body, "local") {
+			if strings.HasPrefix(l, prefix) {
+				locals[l] = true
+			}
+		}
+	}
+	for _, b := range m.blocks("locals") {
+		for _, attr := range b.block.Body.Attributes {
+			if !locals[attr.Name] {
+				continue
+			}
+			for _, t := range attr.Expr.Variables() {
+				if v := refName(t, "var"); strings.HasPrefix(v, prefix) {
+					vars[v] = true
+				}
+			}
+		}
+	}
+}
+
+// bodyRefs returns the names of the root.<name> references in body and its
+// nested blocks.
+func bodyRefs(body *hclsyntax.Body, root string) []string {
+	var names []string
+	for _, attr := range body.Attributes {
+		for _, t := range attr.Expr.Variables() {
+			if n := refName(t, root); n != "" {
+				names = append(names, n)
+			}
+		}
+	}
+	for _, b := range body.Blocks {
+		names = append(names, bodyRefs(b.Body, root)...)
+	}
+	return names
+}
+
+// refName returns the name of a root.<name> reference, or "".
+func refName(t hcl.Traversal, root string) string {
+	if len(t) < 2 || t.RootName() != root {
+		return ""
+	}
+	if step, ok := t[1].(hcl.TraverseAttr); ok {
+		return step.Name
+	}
+	return ""
+}
+
+// writeModules applies the pending edits of the modules and returns the
+// files it wrote. Nil modules are skipped.
+func writeModules(modules []*moduleConfig) ([]string, error) {
+	var written []string
+	for _, m := range modules {
+		if m == nil {
+			continue
+		}
+		for _, f := range append(m.files, m.tfvars...) {
+			if len(f.edits) == 0 {
+				continue
+			}
+			if err := WriteFile(f.path, f.apply()); err != nil {
+				return written, err
+			}
+			written = append(written, f.path)
+		}
+	}
+	return written, nil
+}
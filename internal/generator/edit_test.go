@@ -0,0 +1,187 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var cloudflare = ProviderConfig{Name: "cloudflare", Source: "cloudflare/cloudflare", LatestVersion: "4.20.0"}
+
+func snapshot(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	for _, name := range projectFiles(t, dir) {
+		files[name] = readFile(t, dir, name)
+	}
+	return files
+}
+
+func TestAddRemoveProvider_RoundTrip(t *testing.T) {
+	tests := []struct {
+		layout string
+		checks map[string][]string
+	}{
+		{
+			layout: LayoutSingle,
+			checks: map[string][]string{
+				"provider.tf": {
+					"    cloudflare = {\n      source  = \"cloudflare/cloudflare\"\n      version = \"4.20.0\"\n    }\n  }",
+					"}\n\nprovider \"cloudflare\" {\n  api_token = local.cloudflare_api_token\n}\n\nlocals {",
+					"  cloudflare_api_token = var.cloudflare_api_token\n\n  tags = {",
+				},
+				"variables.tf":     {"\n\nvariable \"cloudflare_api_token\" {\n"},
+				"terraform.tfvars": {"\n\ncloudflare_api_token = \"your-cloudflare-token\"\n"},
+			},
+		},
+		{
+			layout: LayoutTfvars,
+			checks: map[string][]string{
+				"envs/dev.tfvars":  {`cloudflare_api_token = "your-cloudflare-token"`},
+				"envs/prod.tfvars": {`cloudflare_api_token = "your-cloudflare-token"`},
+			},
+		},
+		{
+			layout: LayoutDirs,
+			checks: map[string][]string{
+				"envs/dev/provider.tf":       {`provider "cloudflare" {`},
+				"envs/prod/variables.tf":     {`variable "cloudflare_api_token" {`},
+				"envs/prod/terraform.tfvars": {`cloudflare_api_token = "your-cloudflare-token"`},
+				"modules/main/versions.tf":   {"    cloudflare = {\n      source = \"cloudflare/cloudflare\"\n    }\n"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run("layout="+tt.layout, func(t *testing.T) {
+			data := testData()
+			data.Layout = tt.layout
+			data.Environments = []string{"dev", "prod"}
+
+			dir := t.TempDir()
+			if err := WriteProject(dir, data); err != nil {
+				t.Fatalf("WriteProject() error = %v", err)
+			}
+			before := snapshot(t, dir)
+
+			if _, err := AddProvider(dir, cloudflare); err != nil {
+				t.Fatalf("AddProvider() error = %v", err)
+			}
+			for name, want := range tt.checks {
+				content := readFile(t, dir, name)
+				for _, s := range want {
+					if !strings.Contains(content, s) {
+						t.Errorf("%s = %s\nwant it to contain %s", name, content, s)
+					}
+				}
+			}
+			if _, err := AddProvider(dir, cloudflare); err == nil {
+				t.Error("AddProvider() twice error = nil")
+			}
+
+			if _, err := RemoveProvider(dir, cloudflare); err != nil {
+				t.Fatalf("RemoveProvider() error = %v", err)
+			}
+			after := snapshot(t, dir)
+			for name, content := range before {
+				if after[name] != content {
+					t.Errorf("%s after add and remove =\n%s\nwant\n%s", name, after[name], content)
+				}
+			}
+		})
+	}
+}
+
+func TestAddProvider_ExistingVariable(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteProject(dir, testData()); err != nil {
+		t.Fatal(err)
+	}
+	variables := filepath.Join(dir, "variables.tf")
+	content := readFile(t, dir, "variables.tf") + "\nvariable \"cloudflare_api_token\" {\n  type = string\n}\n"
+	if err := os.WriteFile(variables, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := AddProvider(dir, cloudflare); err != nil {
+		t.Fatalf("AddProvider() error = %v", err)
+	}
+	if got := readFile(t, dir, "variables.tf"); strings.Count(got, `variable "cloudflare_api_token"`) != 1 {
+		t.Errorf("variables.tf = %s\nwant the existing variable kept alone", got)
+	}
+}
+
+func TestRemoveProvider_StillUsed(t *testing.T) {
+	tests := map[string]struct {
+		main string
+		want string
+	}{
+		"resource": {
+			main: "resource \"aws_s3_bucket\" \"logs\" {\n  bucket = \"logs\"\n}\n",
+			want: "aws_s3_bucket.logs (main.tf:1)",
+		},
+		"explicit provider": {
+			main: "resource \"random_id\" \"suffix\" {\n  provider = aws.west\n}\n",
+			want: "random_id.suffix (main.tf:1)",
+		},
+		"data source": {
+			main: "\ndata \"aws_caller_identity\" \"current\" {}\n",
+			want: "data.aws_caller_identity.current (main.tf:2)",
+		},
+		"variable": {
+			main: "output \"region\" {\n  value = var.aws_region\n}\n",
+			want: "var.aws_region (main.tf:2)",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := WriteProject(dir, testData()); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(tt.main), 0644); err != nil {
+				t.Fatal(err)
+			}
+			before := snapshot(t, dir)
+
+			_, err := RemoveProvider(dir, ProviderConfig{Name: "aws"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("RemoveProvider() error = %v, want it to mention %s", err, tt.want)
+			}
+			if after := snapshot(t, dir); len(after) != len(before) || after["provider.tf"] != before["provider.tf"] {
+				t.Error("RemoveProvider() changed files after refusing")
+			}
+		})
+	}
+}
+
+func TestRemoveProvider_NotInProject(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteProject(dir, testData()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RemoveProvider(dir, cloudflare); err == nil {
+		t.Error("RemoveProvider() error = nil for a provider not in the project")
+	}
+}
+
+func TestAddRemoveProvider_LabelLessBlocks(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteProject(dir, testData()); err != nil {
+		t.Fatal(err)
+	}
+	main := "provider {}\n\nvariable {}\n\nmodule {\n  source = \"./vpc\"\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(main), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := AddProvider(dir, cloudflare); err != nil {
+		t.Fatalf("AddProvider() error = %v", err)
+	}
+	if _, err := RemoveProvider(dir, cloudflare); err != nil {
+		t.Fatalf("RemoveProvider() error = %v", err)
+	}
+	if got := readFile(t, dir, "main.tf"); got != main {
+		t.Errorf("main.tf = %s\nwant it left alone", got)
+	}
+}
@@ -2,214 +2,197 @@ package generator
 
 import (
 	"bytes"
+	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 	"text/template"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"warike/base/internal/catalog"
+	"warike/base/internal/flavor"
+	"warike/base/internal/semver"
 )
 
 type ProviderConfig struct {
 	Name          string
 	Source        string
 	LatestVersion string
+
+	// Definition describes the provider block, locals, variables and tfvars
+	// entries. When left empty it is looked up by Name in the embedded
+	// catalog.
+	Definition catalog.Provider
+
+	// Aliases are additional configurations of the provider, each with a
+	// provider block of its own.
+	Aliases []Alias
 }
 
 type GeneratorData struct {
 	ProjectName string
 	Providers   []ProviderConfig
-}
 
-const providerTemplate = `terraform {
-  required_providers {
-{{- range .Providers }}
-    {{ .Name }} = {
-      source  = "{{ .Source }}"
-      version = "{{ .LatestVersion }}"
-    }
-{{- end }}
-  }
-}
+	// Backend is the remote state backend, nil for local state.
+	Backend *Backend
 
-{{ range .Providers -}}
-provider "{{ .Name }}" {
-{{- if eq .Name "aws" }}
-  region  = local.aws_region
-  profile = local.aws_profile
-  default_tags {
-   tags = local.tags
-  }
-{{- else if eq .Name "google" }}
-  project = local.gcp_project_id
-  region  = local.gcp_region
-{{- else if eq .Name "azurerm" }}
-  subscription_id = local.azure_subscription_id
-  features {}
-{{- else if eq .Name "github" }}
-  owner = local.gh_owner
-  token = local.gh_token
-{{- else if eq .Name "vercel" }}
-  api_token = local.vercel_api_token
-{{- else if eq .Name "cloudflare" }}
-  api_token = local.cloudflare_api_token
-{{- end }}
-}
+	// Environments are the deployment environments of the project,
+	// DefaultEnvironments when empty, and Layout how they are laid out.
+	Environments []string
+	Layout       string
 
-{{ end -}}
-
-locals {
-  project_name = var.project_name
-{{- range .Providers }}
-{{- if eq .Name "aws" }}
-  aws_region  = var.aws_region
-  aws_profile = var.aws_profile
-{{- else if eq .Name "google" }}
-  gcp_project_id = var.google_project_id
-  gcp_region     = var.google_region
-{{- else if eq .Name "azurerm" }}
-  azure_location            = var.azure_location
-  azure_subscription_id     = var.azure_subscription_id
-{{- else if eq .Name "github" }}
-  gh_owner           = var.gh_owner
-  gh_token           = var.gh_token
-{{- else if eq .Name "vercel" }}
-  vercel_api_token = var.vercel_api_token
-{{- else if eq .Name "cloudflare" }}
-  cloudflare_api_token = var.cloudflare_api_token
-{{- end }}
-{{- end }}
-
-  tags = {
-    project     = local.project_name
-    environment = "dev"
-    owner       = "warike"
-    cost-center  = "development"
-    terraform   = "true"
-  }
-}
-`
+	// Environment is set while rendering a template once per environment.
+	Environment string
 
-const variablesTemplate = `variable "project_name" {
-  description = "Name of the project"
-  type        = string
-  default     = "my_project"
-}
-{{ range .Providers }}
-{{- if eq .Name "aws" }}
-variable "aws_region" {
-  description = "AWS region"
-  type        = string
-  default     = "us-west-2"
-}
-
-variable "aws_profile" {
-  description = "AWS profile name"
-  type        = string
-  default     = "default"
-}
-{{- else if eq .Name "google" }}
-variable "google_project_id" {
-  description = "Google Cloud project ID"
-  type        = string
-}
+	// Flavor is the tool the project is written for. OpenTofu projects can
+	// scaffold state encryption and use .tofu instead of .tf files.
+	Flavor     flavor.Flavor
+	Encryption bool
+	TofuFiles  bool
 
-variable "google_region" {
-  description = "Google Cloud region"
-  type        = string
-  default     = "us-central1"
-}
-{{- else if eq .Name "azurerm" }}
-variable "azure_location" {
-  description = "Azure location"
-  type        = string
-  default     = "East US"
+	// RequiredVersion is the required_version constraint of the project,
+	// left out when empty.
+	RequiredVersion string
 }
 
-variable "azure_subscription_id" {
-  description = "Azure subscription ID"
-  type        = string
-  sensitive   = true
-}
-{{- else if eq .Name "github" }}
-variable "gh_owner" {
-  description = "GitHub owner (user or organization)"
-  type        = string
-  default     = "warike"
+// RequiredVersion returns the required_version constraint generated for
+// projects when latest is the newest Terraform or OpenTofu release: a
+// floor at its minor version, such as ">= 1.9".
+func RequiredVersion(latest semver.Version) string {
+	return ">= " + latest.Format(2)
 }
 
-variable "gh_token" {
-  description = "GitHub token"
-  type        = string
-  sensitive   = true
-}
-{{- else if eq .Name "vercel" }}
-variable "vercel_api_token" {
-  description = "Vercel API Token"
-  type        = string
-  sensitive   = true
-}
-{{- else if eq .Name "cloudflare" }}
-variable "cloudflare_api_token" {
-  description = "Cloudflare API Token"
-  type        = string
-  sensitive   = true
+// Envs returns the environments of the project.
+func (d GeneratorData) Envs() []string {
+	if len(d.Environments) == 0 {
+		return DefaultEnvironments
+	}
+	return d.Environments
 }
-{{- end }}
-{{- end }}
-`
-
-const tfvarsTemplate = `project_name = "{{ .ProjectName }}"
-{{ range .Providers }}
-{{- if eq .Name "aws" }}
-aws_region   = "us-west-2"
-aws_profile  = "default"
-{{- else if eq .Name "google" }}
-google_project_id = "gcp-project-id-goes-here"
-google_region     = "us-central1"
-{{- else if eq .Name "azurerm" }}
-azure_location = "East US"
-azure_subscription_id = "azure-subscription-id-goes-here"
-{{- else if eq .Name "github" }}
-gh_owner = "warike"
-gh_token = "your-github-token"
-{{- else if eq .Name "vercel" }}
-vercel_api_token = "your-vercel-token"
-{{- else if eq .Name "cloudflare" }}
-cloudflare_api_token = "your-cloudflare-token"
-{{- end }}
-{{- end }}
-`
-
-const mainTemplate = `// main.tf
-`
 
 func GenerateProviderFile(data GeneratorData) ([]byte, error) {
-	return generateFromTemplate("provider", providerTemplate, data)
+	return generateBuiltin("provider.tf", data)
 }
 
 func GenerateVariablesFile(data GeneratorData) ([]byte, error) {
-	return generateFromTemplate("variables", variablesTemplate, data)
+	return generateBuiltin("variables.tf", data)
 }
 
 func GenerateTfvarsFile(data GeneratorData) ([]byte, error) {
-	return generateFromTemplate("tfvars", tfvarsTemplate, data)
+	return generateBuiltin("terraform.tfvars", data)
 }
 
 func GenerateMainFile(data GeneratorData) ([]byte, error) {
-	return generateFromTemplate("main", mainTemplate, data)
-}
+	return generateBuiltin("main.tf", data)
+}
+
+var funcs = template.FuncMap{
+	"indent": func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+		for i, line := range lines {
+			if line != "" {
+				lines[i] = pad + line
+			}
+		}
+		return strings.Join(lines, "\n")
+	},
+	"hclString": func(s string) string {
+		return string(hclwrite.TokensForValue(cty.StringVal(s)).Bytes())
+	},
+	"join": strings.Join,
+	"hclStrings": func(list []string) string {
+		vals := make([]cty.Value, len(list))
+		for i, s := range list {
+			vals[i] = cty.StringVal(s)
+		}
+		return string(hclwrite.TokensForValue(cty.TupleVal(vals)).Bytes())
+	},
+}
+
+// generateBuiltin renders the built-in template of the named file.
+func generateBuiltin(name string, data GeneratorData) ([]byte, error) {
+	text, err := fs.ReadFile(builtinFS(), name+TemplateExt)
+	if err != nil {
+		return nil, err
+	}
 
-func generateFromTemplate(name, text string, data GeneratorData) ([]byte, error) {
-	tmpl, err := template.New(name).Parse(text)
+	tmpl, err := template.New(name).Funcs(funcs).Parse(string(text))
 	if err != nil {
 		return nil, err
 	}
 
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
+	if err := tmpl.Execute(&buf, data.withDefinitions()); err != nil {
 		return nil, err
 	}
 
-	return buf.Bytes(), nil
+	// Align attributes the way terraform fmt would.
+	return hclwrite.Format(buf.Bytes()), nil
+}
+
+// withDefinitions returns a copy of d where providers without a definition
+// use the embedded catalog entry of the same name, extended with the
+// locals and variables of their aliases.
+func (d GeneratorData) withDefinitions() GeneratorData {
+	out := d
+	out.Providers = make([]ProviderConfig, len(d.Providers))
+	copy(out.Providers, d.Providers)
+
+	var embedded *catalog.Catalog
+	for i, p := range out.Providers {
+		if p.Definition.Name != "" {
+			continue
+		}
+		if embedded == nil {
+			embedded = catalog.Embedded()
+		}
+		if def, ok := embedded.Get(p.Name); ok {
+			out.Providers[i].Definition = def
+		}
+	}
+	for i, p := range out.Providers {
+		out.Providers[i].Definition = p.withAliasDefinitions()
+	}
+	return out
 }
 
 func WriteFile(filename string, content []byte) error {
 	return os.WriteFile(filename, content, 0644)
 }
+
+// ProjectName derives the project name from the target directory, falling
+// back to "my_project" for the current directory.
+func ProjectName(targetDir string) string {
+	if targetDir == "" || targetDir == "." {
+		return "my_project"
+	}
+	return filepath.Base(targetDir)
+}
+
+// WriteProject renders every project file for data into targetDir, creating
+// the directory when needed. Templates in templateDirs override or add to the
+// built-in ones, see Templates.
+func WriteProject(targetDir string, data GeneratorData, templateDirs ...string) error {
+	files, err := NewTemplates(templateDirs...).Render(data)
+	if err != nil {
+		return err
+	}
+
+	if targetDir == "" {
+		targetDir = "."
+	}
+	for _, f := range files {
+		path := filepath.Join(targetDir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(path), err)
+		}
+		if err := WriteFile(path, f.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,8 +1,16 @@
 package generator
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+
+	"warike/base/internal/catalog"
+	"warike/base/internal/flavor"
+	"warike/base/internal/semver"
 )
 
 func TestGenerateProviderFile_AWS(t *testing.T) {
@@ -35,3 +43,119 @@ func TestGenerateProviderFile_AWS(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateProviderFile_RequiredVersion(t *testing.T) {
+	data := GeneratorData{
+		ProjectName:     "test-project",
+		Providers:       []ProviderConfig{{Name: "aws", Source: "hashicorp/aws", LatestVersion: "5.30.0"}},
+		RequiredVersion: RequiredVersion(semver.MustParse("1.9.5")),
+	}
+
+	got, err := GenerateProviderFile(data)
+	if err != nil {
+		t.Fatalf("GenerateProviderFile() error = %v", err)
+	}
+	want := "terraform {\n  required_version = \">= 1.9\"\n\n  required_providers {\n"
+	if !strings.HasPrefix(string(got), want) {
+		t.Errorf("GenerateProviderFile() = %s, want it to start with %q", got, want)
+	}
+}
+
+func TestGenerateFiles_CatalogDefinition(t *testing.T) {
+	def := catalog.Provider{
+		Name:   "datadog",
+		Source: "DataDog/datadog",
+		Config: "api_key = local.datadog_api_key",
+		Locals: []catalog.Local{{Name: "datadog_api_key", Value: "var.datadog_api_key"}},
+		Variables: []catalog.Variable{{
+			Name:        "datadog_api_key",
+			Description: "Datadog API key",
+			Sensitive:   true,
+			Tfvars:      cty.StringVal("your-datadog-api-key"),
+		}},
+	}
+	data := GeneratorData{
+		ProjectName: "test-project",
+		Providers: []ProviderConfig{
+			{Name: "datadog", Source: "DataDog/datadog", LatestVersion: "3.40.0", Definition: def},
+			{Name: "random", Source: "hashicorp/random", LatestVersion: "3.6.0"},
+		},
+	}
+
+	checks := []struct {
+		gen  func(GeneratorData) ([]byte, error)
+		want []string
+	}{
+		{GenerateProviderFile, []string{
+			"provider \"datadog\" {\n  api_key = local.datadog_api_key\n}",
+			`provider "random" {}`,
+			`datadog_api_key = var.datadog_api_key`,
+		}},
+		{GenerateVariablesFile, []string{
+			`variable "datadog_api_key" {`,
+			`description = "Datadog API key"`,
+			`sensitive   = true`,
+		}},
+		{GenerateTfvarsFile, []string{
+			`datadog_api_key = "your-datadog-api-key"`,
+		}},
+	}
+
+	for _, c := range checks {
+		got, err := c.gen(data)
+		if err != nil {
+			t.Fatalf("generate error = %v", err)
+		}
+		for _, s := range c.want {
+			if !strings.Contains(string(got), s) {
+				t.Errorf("Expected content to contain %q:\n%s", s, got)
+			}
+		}
+	}
+}
+
+func TestWriteProject_OpenTofu(t *testing.T) {
+	data := GeneratorData{
+		ProjectName: "test-project",
+		Providers: []ProviderConfig{
+			{Name: "aws", Source: "hashicorp/aws", LatestVersion: "5.30.0"},
+		},
+		Backend:    &Backend{Type: "s3"},
+		Flavor:     flavor.OpenTofu,
+		Encryption: true,
+		TofuFiles:  true,
+	}
+
+	dir := t.TempDir()
+	if err := WriteProject(dir, data); err != nil {
+		t.Fatalf("WriteProject() error = %v", err)
+	}
+
+	for _, name := range []string{"provider.tofu", "variables.tofu", "main.tofu", "backend.tofu", "encryption.tofu", "terraform.tfvars"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "provider.tf")); !os.IsNotExist(err) {
+		t.Errorf("provider.tf should be written as provider.tofu, stat error = %v", err)
+	}
+
+	encryption, _ := os.ReadFile(filepath.Join(dir, "encryption.tofu"))
+	for _, s := range []string{`key_provider "pbkdf2" "main" {`, "passphrase = var.state_encryption_passphrase", `method "unencrypted" "migrate" {}`} {
+		if !strings.Contains(string(encryption), s) {
+			t.Errorf("Expected encryption.tofu to contain %q:\n%s", s, encryption)
+		}
+	}
+
+	backend, _ := os.ReadFile(filepath.Join(dir, "backend.tofu"))
+	if !strings.Contains(string(backend), "tofu init -backend-config") {
+		t.Errorf("Expected a tofu init hint in backend.tofu:\n%s", backend)
+	}
+}
+
+func TestWriteProject_EncryptionNeedsOpenTofu(t *testing.T) {
+	data := GeneratorData{ProjectName: "test-project", Encryption: true}
+	if err := WriteProject(t.TempDir(), data); err == nil {
+		t.Error("Expected an error for encryption with the terraform flavor")
+	}
+}
@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Project layouts for several environments.
+const (
+	// LayoutSingle is one root module with a single terraform.tfvars.
+	LayoutSingle = ""
+	// LayoutTfvars is one root module with a tfvars file per environment,
+	// envs/<env>.tfvars.
+	LayoutTfvars = "tfvars"
+	// LayoutDirs is a root module per environment, envs/<env>/, calling the
+	// shared module in modules/main.
+	LayoutDirs = "dirs"
+	// LayoutWorkspaces is one root module with a Terraform workspace per
+	// environment.
+	LayoutWorkspaces = "workspaces"
+)
+
+// Layouts lists the layouts by their ParseLayout name.
+var Layouts = []string{"single", LayoutTfvars, LayoutDirs, LayoutWorkspaces}
+
+// ParseLayout parses a layout name; "" and "single" are LayoutSingle.
+func ParseLayout(s string) (string, error) {
+	switch s {
+	case "", "single":
+		return LayoutSingle, nil
+	case LayoutTfvars, LayoutDirs, LayoutWorkspaces:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown layout %q (available: %s)", s, strings.Join(Layouts, ", "))
+}
+
+// Validate reports combinations of layout, environments, backend and flavor
+// that cannot work, and provider aliases their definitions do not support.
+func (d GeneratorData) Validate() error {
+	if _, err := ParseLayout(d.Layout); err != nil {
+		return err
+	}
+	for _, env := range d.Environments {
+		if env == "" || path.Base(env) != env || strings.HasPrefix(env, ".") {
+			return fmt.Errorf("invalid environment name %q", env)
+		}
+	}
+	if (d.Encryption || d.TofuFiles) && !d.Flavor.IsOpenTofu() {
+		return fmt.Errorf("state encryption and .tofu files need the tofu flavor")
+	}
+	if d.Layout == LayoutWorkspaces && d.Backend != nil && d.Backend.Type == "http" {
+		return fmt.Errorf("the http backend does not support workspaces; use another layout or backend")
+	}
+	for _, p := range d.withDefinitions().Providers {
+		if err := p.checkAliases(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnvironmentExpr is the HCL expression of the environment name in the root
+// module: a variable set per environment with the tfvars layout, the
+// workspace with the workspaces layout, and a literal otherwise.
+func (d GeneratorData) EnvironmentExpr() string {
+	switch d.Layout {
+	case LayoutTfvars:
+		return "var.environment"
+	case LayoutWorkspaces:
+		return "terraform.workspace"
+	}
+	env := d.Environment
+	if env == "" {
+		env = d.Envs()[0]
+	}
+	return hclValue(env)
+}
+
+// perEnvironmentRoot reports whether the root module templates are rendered
+// once per environment.
+func (d GeneratorData) perEnvironmentRoot() bool {
+	return d.Layout == LayoutDirs
+}
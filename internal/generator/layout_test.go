@@ -0,0 +1,146 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func projectFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestParseLayout(t *testing.T) {
+	for in, want := range map[string]string{"": LayoutSingle, "single": LayoutSingle, "dirs": LayoutDirs} {
+		if got, err := ParseLayout(in); err != nil || got != want {
+			t.Errorf("ParseLayout(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseLayout("monorepo"); err == nil {
+		t.Error("ParseLayout(monorepo) error = nil")
+	}
+}
+
+func TestWriteProject_Layouts(t *testing.T) {
+	tests := []struct {
+		layout string
+		files  []string
+		checks map[string][]string
+	}{
+		{
+			layout: LayoutTfvars,
+			files: []string{
+				"backend.tf", "envs/dev.backend.hcl", "envs/dev.tfvars", "envs/prod.backend.hcl", "envs/prod.tfvars",
+				"main.tf", "provider.tf", "variables.tf",
+			},
+			checks: map[string][]string{
+				"provider.tf":           {"environment = var.environment"},
+				"variables.tf":          {`variable "environment" {`},
+				"envs/prod.tfvars":      {`environment  = "prod"`, `project_name = "demo"`, `aws_region  = "us-west-2"`},
+				"envs/prod.backend.hcl": {`key          = "demo/prod/terraform.tfstate"`},
+			},
+		},
+		{
+			layout: LayoutDirs,
+			files: []string{
+				"envs/dev/backend.hcl", "envs/dev/backend.tf", "envs/dev/main.tf", "envs/dev/provider.tf", "envs/dev/terraform.tfvars", "envs/dev/variables.tf",
+				"envs/prod/backend.hcl", "envs/prod/backend.tf", "envs/prod/main.tf", "envs/prod/provider.tf", "envs/prod/terraform.tfvars", "envs/prod/variables.tf",
+				"modules/main/main.tf", "modules/main/variables.tf", "modules/main/versions.tf",
+			},
+			checks: map[string][]string{
+				"envs/prod/provider.tf":    {`environment = "prod"`},
+				"envs/prod/main.tf":        {`source = "../../modules/main"`, `environment  = "prod"`},
+				"envs/prod/backend.hcl":    {`key          = "demo/prod/terraform.tfstate"`},
+				"envs/prod/backend.tf":     {"-backend-config=backend.hcl"},
+				"modules/main/versions.tf": {`source = "hashicorp/aws"`},
+			},
+		},
+		{
+			layout: LayoutWorkspaces,
+			files:  []string{"backend.hcl", "backend.tf", "main.tf", "provider.tf", "terraform.tfvars", "variables.tf"},
+			checks: map[string][]string{
+				"provider.tf": {"environment = terraform.workspace"},
+				"main.tf":     {`contains(["dev", "prod"], terraform.workspace)`},
+				"backend.hcl": {`key          = "demo/terraform.tfstate"`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			data := testData()
+			data.Layout = tt.layout
+			data.Environments = []string{"dev", "prod"}
+			data.Backend = &Backend{Type: "s3"}
+
+			dir := t.TempDir()
+			if err := WriteProject(dir, data); err != nil {
+				t.Fatalf("WriteProject() error = %v", err)
+			}
+
+			if got := projectFiles(t, dir); strings.Join(got, "\n") != strings.Join(tt.files, "\n") {
+				t.Errorf("files = %v\nwant %v", got, tt.files)
+			}
+			for name, want := range tt.checks {
+				content := readFile(t, dir, name)
+				for _, s := range want {
+					if !strings.Contains(content, s) {
+						t.Errorf("%s = %s\nwant it to contain %s", name, content, s)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWriteProject_DirsLayoutCloud(t *testing.T) {
+	data := testData()
+	data.Layout = LayoutDirs
+	data.Environments = []string{"prod"}
+	data.Backend = &Backend{Type: "cloud"}
+
+	dir := t.TempDir()
+	if err := WriteProject(dir, data); err != nil {
+		t.Fatalf("WriteProject() error = %v", err)
+	}
+	if got := readFile(t, dir, "envs/prod/backend.tf"); !strings.Contains(got, `name = "demo-prod"`) {
+		t.Errorf("backend.tf = %s\nwant a workspace per environment", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := map[string]GeneratorData{
+		"unknown layout":       {Layout: "monorepo"},
+		"environment path":     {Environments: []string{"../prod"}},
+		"http with workspaces": {Layout: LayoutWorkspaces, Backend: &Backend{Type: "http"}},
+	}
+	for name, data := range tests {
+		if err := data.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil", name)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+
+	"warike/base/internal/gitsource"
+)
+
+// TemplateExt marks the files of a template directory that are rendered.
+// Other files are ignored.
+const TemplateExt = ".tmpl"
+
+// BuiltinPrefix names the built-in templates inside user templates, so an
+// override can wrap the default output, e.g.
+// {{ template "builtin/provider.tf.tmpl" . }}.
+const BuiltinPrefix = "builtin/"
+
+//go:embed templates
+var builtinFiles embed.FS
+
+func builtinFS() fs.FS {
+	sub, err := fs.Sub(builtinFiles, "templates")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// Templates is an ordered chain of template directories. A file in an
+// earlier directory overrides the file at the same relative path in a later
+// one; the built-in templates always come last.
+type Templates struct {
+	layers []fs.FS
+}
+
+// NewTemplates returns the chain of dirs, highest priority first, followed
+// by the built-in templates.
+func NewTemplates(dirs ...string) *Templates {
+	t := &Templates{}
+	for _, dir := range dirs {
+		t.layers = append(t.layers, os.DirFS(dir))
+	}
+	t.layers = append(t.layers, builtinFS())
+	return t
+}
+
+// File is a rendered project file.
+type File struct {
+	Path    string
+	Content []byte
+}
+
+// Render executes every template of the chain with data. Both the content
+// and the file name of a template are templates; a template whose name or
+// content renders to nothing produces no file, which is how an override
+// drops a built-in file. A template whose name uses .Environment is
+// rendered once for every environment of data. With LayoutDirs, the root
+// module templates, all but those under modules/, are rendered into
+// envs/<env>/ once for every environment. With TofuFiles, .tf files are
+// written as .tofu files.
+func (t *Templates) Render(data GeneratorData) ([]File, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+	data = data.withDefinitions()
+
+	base, err := parseBuiltins()
+	if err != nil {
+		return nil, err
+	}
+
+	sources, err := t.sources()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	seen := make(map[string]string)
+	for _, src := range sources {
+		tmpl, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tmpl.New(src.path).Parse(string(src.text)); err != nil {
+			return nil, err
+		}
+
+		// Templates named after the environment are rendered once per
+		// environment, and so is the root module with the dirs layout.
+		envs := []string{""}
+		relocate := false
+		if strings.Contains(src.path, ".Environment") {
+			envs = data.Envs()
+		} else if data.perEnvironmentRoot() && !strings.HasPrefix(src.path, "modules/") {
+			envs = data.Envs()
+			relocate = true
+		}
+
+		for _, env := range envs {
+			envData := data
+			envData.Environment = env
+
+			name, err := renderName(src.path, envData)
+			if err != nil {
+				return nil, err
+			}
+			if name == "" {
+				continue
+			}
+			if relocate {
+				name = path.Join("envs", env, name)
+			}
+			if data.TofuFiles && path.Ext(name) == ".tf" {
+				name = strings.TrimSuffix(name, ".tf") + ".tofu"
+			}
+			if prev, ok := seen[name]; ok {
+				return nil, fmt.Errorf("templates %s and %s both render to %s", prev, src.path, name)
+			}
+			seen[name] = src.path
+
+			var buf bytes.Buffer
+			if err := tmpl.ExecuteTemplate(&buf, src.path, envData); err != nil {
+				return nil, err
+			}
+			if len(bytes.TrimSpace(buf.Bytes())) == 0 {
+				continue
+			}
+
+			content := buf.Bytes()
+			if isHCL(name) {
+				// Align attributes the way terraform fmt would.
+				content = hclwrite.Format(content)
+			}
+			files = append(files, File{Path: name, Content: content})
+		}
+	}
+	return files, nil
+}
+
+type templateSource struct {
+	path string
+	text []byte
+}
+
+// sources returns the winning template for every relative path of the
+// chain, sorted by path.
+func (t *Templates) sources() ([]templateSource, error) {
+	found := make(map[string]templateSource)
+	for _, layer := range t.layers {
+		err := fs.WalkDir(layer, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if p != "." && strings.HasPrefix(d.Name(), ".") {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if !strings.HasSuffix(p, TemplateExt) {
+				return nil
+			}
+			if _, ok := found[p]; ok {
+				return nil
+			}
+			text, err := fs.ReadFile(layer, p)
+			if err != nil {
+				return err
+			}
+			found[p] = templateSource{path: p, text: text}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("reading templates: %w", err)
+		}
+	}
+
+	list := make([]templateSource, 0, len(found))
+	for _, src := range found {
+		list = append(list, src)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].path < list[j].path })
+	return list, nil
+}
+
+// parseBuiltins returns a template set holding every built-in template
+// under BuiltinPrefix.
+func parseBuiltins() (*template.Template, error) {
+	builtins := builtinFS()
+	base := template.New("").Funcs(funcs)
+	err := fs.WalkDir(builtins, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		text, err := fs.ReadFile(builtins, p)
+		if err != nil {
+			return err
+		}
+		_, err = base.New(BuiltinPrefix + p).Parse(string(text))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return base, nil
+}
+
+// renderName executes the template path p and strips TemplateExt. The result
+// must stay inside the project directory.
+func renderName(p string, data GeneratorData) (string, error) {
+	tmpl, err := template.New(p).Funcs(funcs).Parse(strings.TrimSuffix(p, TemplateExt))
+	if err != nil {
+		return "", fmt.Errorf("template name %s: %w", p, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("template name %s: %w", p, err)
+	}
+
+	name := strings.TrimSpace(buf.String())
+	if name == "" || strings.HasSuffix(name, "/") {
+		return "", nil
+	}
+	name = path.Clean(name)
+	if !fs.ValidPath(name) {
+		return "", fmt.Errorf("template %s renders to %s, outside the project directory", p, name)
+	}
+	return name, nil
+}
+
+func isHCL(name string) bool {
+	switch path.Ext(name) {
+	case ".tf", ".tofu", ".tfvars", ".hcl":
+		return true
+	}
+	return false
+}
+
+// ResolveTemplateDir turns a --template argument into a local directory.
+// Directories are used as is; git URLs (git::, git@, ssh://, https://...,
+// or a .git suffix) are shallow cloned into a temporary directory, which
+// cleanup removes. A ?ref= query selects a branch or tag and a // path
+// separator selects a subdirectory, as in Terraform module sources.
+// Canceling ctx kills the clone.
+func ResolveTemplateDir(ctx context.Context, spec string) (dir string, cleanup func(), err error) {
+	cleanup = func() {}
+	if info, statErr := os.Stat(spec); statErr == nil {
+		if !info.IsDir() {
+			return "", cleanup, fmt.Errorf("template %s is not a directory", spec)
+		}
+		return spec, cleanup, nil
+	}
+	if !gitsource.IsURL(spec) {
+		return "", cleanup, fmt.Errorf("template directory %s not found", spec)
+	}
+
+	url, ref, subdir := gitsource.Split(spec)
+	if err := gitsource.CheckURL(url); err != nil {
+		return "", cleanup, err
+	}
+	tmp, err := os.MkdirTemp("", "tfinit-template-")
+	if err != nil {
+		return "", cleanup, err
+	}
+	cleanup = func() { os.RemoveAll(tmp) }
+
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	args = append(args, "--", url, tmp)
+	if out, err := exec.CommandContext(ctx, "git", args...).CombinedOutput(); err != nil {
+		cleanup()
+		return "", func() {}, fmt.Errorf("cloning %s: %w: %s", url, err, strings.TrimSpace(string(out)))
+	}
+
+	dir = tmp
+	if subdir != "" {
+		dir = filepath.Join(tmp, filepath.FromSlash(subdir))
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			cleanup()
+			return "", func() {}, fmt.Errorf("template directory %s not found in %s", subdir, url)
+		}
+	}
+	return dir, cleanup, nil
+}
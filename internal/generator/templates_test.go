@@ -0,0 +1,172 @@
+package generator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTemplates(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func testData() GeneratorData {
+	return GeneratorData{
+		ProjectName: "demo",
+		Providers: []ProviderConfig{
+			{Name: "aws", Source: "hashicorp/aws", LatestVersion: "5.30.0"},
+		},
+	}
+}
+
+func TestWriteProject_Builtin(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteProject(dir, testData()); err != nil {
+		t.Fatalf("WriteProject() error = %v", err)
+	}
+
+	for _, name := range []string{"provider.tf", "variables.tf", "terraform.tfvars", "main.tf"} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := generateBuiltin(name, testData())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%s differs from the built-in template output", name)
+		}
+	}
+}
+
+func TestWriteProject_TemplateChain(t *testing.T) {
+	house := writeTemplates(t, map[string]string{
+		// Wraps the built-in output with a header.
+		"provider.tf.tmpl": "# Managed by the platform team\n{{ template \"builtin/provider.tf.tmpl\" . }}",
+		// Drops a built-in file.
+		"terraform.tfvars.tmpl": "",
+		// Adds a file with a templated name in a subdirectory.
+		"envs/{{ .ProjectName }}.auto.tfvars.tmpl": "project_name = {{ hclString .ProjectName }}\n",
+		"versions.tf.tmpl":                         "# house versions\n",
+		"README.md":                                "not a template",
+	})
+	team := writeTemplates(t, map[string]string{
+		// Loses against the first directory of the chain.
+		"versions.tf.tmpl": "# team versions\n",
+	})
+
+	dir := t.TempDir()
+	if err := WriteProject(dir, testData(), house, team); err != nil {
+		t.Fatalf("WriteProject() error = %v", err)
+	}
+
+	provider, err := os.ReadFile(filepath.Join(dir, "provider.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(provider), "# Managed by the platform team\n") || !strings.Contains(string(provider), `provider "aws" {`) {
+		t.Errorf("provider.tf = %s", provider)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "terraform.tfvars")); !os.IsNotExist(err) {
+		t.Errorf("terraform.tfvars should not be generated, stat error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "README.md")); !os.IsNotExist(err) {
+		t.Errorf("non-template files should be ignored, stat error = %v", err)
+	}
+
+	tfvars, err := os.ReadFile(filepath.Join(dir, "envs", "demo.auto.tfvars"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(tfvars) != "project_name = \"demo\"\n" {
+		t.Errorf("demo.auto.tfvars = %q", tfvars)
+	}
+
+	versions, err := os.ReadFile(filepath.Join(dir, "versions.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(versions) != "# house versions\n" {
+		t.Errorf("versions.tf = %q, want the first directory to win", versions)
+	}
+
+	for _, name := range []string{"variables.tf", "main.tf"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("built-in %s should still be generated: %v", name, err)
+		}
+	}
+}
+
+func TestRender_Errors(t *testing.T) {
+	tests := map[string]map[string]string{
+		"escapes project":  {"{{ \"../outside.tf\" }}.tmpl": "x = 1\n"},
+		"duplicate output": {"a.tf.tmpl": "a = 1\n", "{{ \"a.tf\" }}.tmpl": "b = 1\n"},
+		"bad template":     {"main.tf.tmpl": "{{ .Nope }"},
+	}
+	for name, files := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewTemplates(writeTemplates(t, files)).Render(testData()); err == nil {
+				t.Error("Render() error = nil")
+			}
+		})
+	}
+}
+
+func TestResolveTemplateDir_Git(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	repo := writeTemplates(t, map[string]string{"house/versions.tf.tmpl": "# cloned\n"})
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch", "main"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "templates"},
+		{"tag", "v1"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	dir, cleanup, err := ResolveTemplateDir(t.Context(), "git::file://" + filepath.ToSlash(repo) + "//house?ref=v1")
+	if err != nil {
+		t.Fatalf("ResolveTemplateDir() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "versions.tf.tmpl")); err != nil {
+		t.Errorf("cloned template missing: %v", err)
+	}
+	cleanup()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("cleanup should remove the clone, stat error = %v", err)
+	}
+}
+
+func TestResolveTemplateDir_Missing(t *testing.T) {
+	if _, _, err := ResolveTemplateDir(t.Context(), filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("ResolveTemplateDir() error = nil for a missing directory")
+	}
+}
+
+func TestResolveTemplateDir_OptionURL(t *testing.T) {
+	if _, _, err := ResolveTemplateDir(t.Context(), "git::--upload-pack=touch pwned"); err == nil || !strings.Contains(err.Error(), "invalid git URL") {
+		t.Errorf("ResolveTemplateDir() error = %v for an option as URL, want invalid git URL", err)
+	}
+}
@@ -0,0 +1,55 @@
+// Package gitsource reads git URLs written like Terraform module sources,
+// git::URL//subdir?ref=REF, for template directories and module calls.
+package gitsource
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IsURL reports whether spec names a git repository: a git:: or git@
+// prefix, a URL scheme git can clone, or a .git suffix.
+func IsURL(spec string) bool {
+	if strings.HasPrefix(spec, "git::") || strings.HasPrefix(spec, "git@") {
+		return true
+	}
+	for _, scheme := range []string{"ssh://", "https://", "http://", "file://", "git://"} {
+		if strings.HasPrefix(spec, scheme) {
+			return true
+		}
+	}
+	url, _, _ := Split(spec)
+	return strings.HasSuffix(url, ".git")
+}
+
+// Split splits git::URL//subdir?ref=REF into its parts.
+func Split(spec string) (url, ref, subdir string) {
+	url = strings.TrimPrefix(spec, "git::")
+	if i := strings.Index(url, "?"); i >= 0 {
+		for _, kv := range strings.Split(url[i+1:], "&") {
+			if v, ok := strings.CutPrefix(kv, "ref="); ok {
+				ref = v
+			}
+		}
+		url = url[:i]
+	}
+
+	start := 0
+	if i := strings.Index(url, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	if i := strings.Index(url[start:], "//"); i >= 0 {
+		subdir = strings.Trim(url[start+i+2:], "/")
+		url = url[:start+i]
+	}
+	return url, ref, subdir
+}
+
+// CheckURL rejects a URL that git would take for an option, such as
+// --upload-pack=<command>.
+func CheckURL(url string) error {
+	if strings.HasPrefix(url, "-") {
+		return fmt.Errorf("invalid git URL %q", url)
+	}
+	return nil
+}
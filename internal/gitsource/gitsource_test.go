@@ -0,0 +1,19 @@
+package gitsource
+
+import "testing"
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		spec, url, ref, subdir string
+	}{
+		{"https://github.com/acme/templates.git", "https://github.com/acme/templates.git", "", ""},
+		{"git::https://github.com/acme/infra.git//templates/aws?ref=v1.2.0", "https://github.com/acme/infra.git", "v1.2.0", "templates/aws"},
+		{"git@github.com:acme/templates.git?ref=main", "git@github.com:acme/templates.git", "main", ""},
+	}
+	for _, tt := range tests {
+		url, ref, subdir := Split(tt.spec)
+		if url != tt.url || ref != tt.ref || subdir != tt.subdir {
+			t.Errorf("Split(%q) = %q, %q, %q, want %q, %q, %q", tt.spec, url, ref, subdir, tt.url, tt.ref, tt.subdir)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Package lockfile reads and rewrites Terraform dependency lock files.
+package lockfile
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FileName is the name Terraform gives the dependency lock file.
+const FileName = ".terraform.lock.hcl"
+
+// DefaultHost is the registry hostname implied by provider sources that
+// omit one.
+const DefaultHost = "registry.terraform.io"
+
+// Provider is one locked provider entry.
+type Provider struct {
+	// Address is the fully qualified provider address, e.g.
+	// "registry.terraform.io/hashicorp/aws".
+	Address     string
+	Version     string
+	Constraints string
+	Hashes      []string
+}
+
+// File is a parsed lock file that can be edited in place.
+type File struct {
+	Path string
+	file *hclwrite.File
+}
+
+// Load parses the lock file at path.
+func Load(path string) (*File, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, src)
+}
+
+// Parse parses lock file content. path is only used in error messages.
+func Parse(path string, src []byte) (*File, error) {
+	f, diags := hclwrite.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return &File{Path: path, file: f}, nil
+}
+
+// Address returns the lock file address for a provider source, adding the
+// default registry hostname when the source has none.
+func Address(source string) string {
+	return AddressOn(DefaultHost, source)
+}
+
+// AddressOn is Address for a tool whose default registry is host, such as
+// registry.opentofu.org for OpenTofu.
+func AddressOn(host, source string) string {
+	source = strings.ToLower(source)
+	if strings.Count(source, "/") == 1 {
+		return host + "/" + source
+	}
+	return source
+}
+
+// Providers returns the locked providers in file order.
+func (f *File) Providers() ([]Provider, error) {
+	var out []Provider
+	for _, block := range f.file.Body().Blocks() {
+		if block.Type() != "provider" || len(block.Labels()) != 1 {
+			continue
+		}
+
+		p := Provider{Address: block.Labels()[0]}
+		var err error
+		if p.Version, err = stringAttr(block, "version"); err != nil {
+			return nil, err
+		}
+		if p.Constraints, err = stringAttr(block, "constraints"); err != nil {
+			return nil, err
+		}
+		if p.Hashes, err = listAttr(block, "hashes"); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// Provider returns the entry locked under address, if any.
+func (f *File) Provider(address string) (Provider, bool, error) {
+	providers, err := f.Providers()
+	if err != nil {
+		return Provider{}, false, err
+	}
+	for _, p := range providers {
+		if p.Address == address {
+			return p, true, nil
+		}
+	}
+	return Provider{}, false, nil
+}
+
+// SetProvider rewrites the block locked under p.Address. Hashes are sorted
+// and written one per line, the way Terraform writes them. It returns false
+// when the lock file has no block for the address.
+func (f *File) SetProvider(p Provider) bool {
+	block := f.file.Body().FirstMatchingBlock("provider", []string{p.Address})
+	if block == nil {
+		return false
+	}
+
+	body := block.Body()
+	body.SetAttributeValue("version", cty.StringVal(p.Version))
+	if p.Constraints != "" {
+		body.SetAttributeValue("constraints", cty.StringVal(p.Constraints))
+	} else {
+		body.RemoveAttribute("constraints")
+	}
+	body.SetAttributeRaw("hashes", hashTokens(p.Hashes))
+	return true
+}
+
+// Bytes returns the formatted lock file content.
+func (f *File) Bytes() []byte {
+	return hclwrite.Format(f.file.Bytes())
+}
+
+func hashTokens(hashes []string) hclwrite.Tokens {
+	sorted := append([]string(nil), hashes...)
+	sort.Strings(sorted)
+
+	toks := hclwrite.Tokens{
+		{Type: hclsyntax.TokenOBrack, Bytes: []byte("[")},
+		{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
+	}
+	for _, h := range sorted {
+		toks = append(toks, hclwrite.TokensForValue(cty.StringVal(h))...)
+		toks = append(toks,
+			&hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte(",")},
+			&hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
+		)
+	}
+	toks = append(toks, &hclwrite.Token{Type: hclsyntax.TokenCBrack, Bytes: []byte("]")})
+	return toks
+}
+
+func stringAttr(block *hclwrite.Block, name string) (string, error) {
+	val, ok, err := attrValue(block, name)
+	if err != nil || !ok {
+		return "", err
+	}
+	if val.Type() != cty.String {
+		return "", fmt.Errorf("provider %q: %s must be a string", block.Labels()[0], name)
+	}
+	return val.AsString(), nil
+}
+
+func listAttr(block *hclwrite.Block, name string) ([]string, error) {
+	val, ok, err := attrValue(block, name)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if !val.Type().IsTupleType() && !val.Type().IsListType() {
+		return nil, fmt.Errorf("provider %q: %s must be a list", block.Labels()[0], name)
+	}
+
+	var out []string
+	for it := val.ElementIterator(); it.Next(); {
+		_, v := it.Element()
+		if v.Type() != cty.String {
+			return nil, fmt.Errorf("provider %q: %s must only contain strings", block.Labels()[0], name)
+		}
+		out = append(out, v.AsString())
+	}
+	return out, nil
+}
+
+// attrValue evaluates a literal attribute of block by reparsing its
+// expression tokens.
+func attrValue(block *hclwrite.Block, name string) (cty.Value, bool, error) {
+	attr := block.Body().GetAttribute(name)
+	if attr == nil {
+		return cty.NilVal, false, nil
+	}
+
+	src := attr.Expr().BuildTokens(nil).Bytes()
+	expr, diags := hclsyntax.ParseExpression(src, name, hcl.InitialPos)
+	if diags.HasErrors() {
+		return cty.NilVal, false, diags
+	}
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return cty.NilVal, false, diags
+	}
+	return val, true, nil
+}
@@ -0,0 +1,111 @@
+package lockfile
+
+import (
+	"strings"
+	"testing"
+)
+
+const sample = `# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.30.0"
+  constraints = "5.30.0"
+  hashes = [
+    "h1:old",
+    "zh:old",
+  ]
+}
+
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.5.1"
+  hashes = [
+    "h1:random",
+  ]
+}
+`
+
+func TestProviders(t *testing.T) {
+	f, err := Parse(FileName, []byte(sample))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	got, err := f.Providers()
+	if err != nil {
+		t.Fatalf("Providers failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 providers, got %d", len(got))
+	}
+	aws := got[0]
+	if aws.Address != "registry.terraform.io/hashicorp/aws" || aws.Version != "5.30.0" || aws.Constraints != "5.30.0" {
+		t.Errorf("Unexpected provider: %+v", aws)
+	}
+	if len(aws.Hashes) != 2 || aws.Hashes[0] != "h1:old" {
+		t.Errorf("Hashes = %v", aws.Hashes)
+	}
+}
+
+func TestSetProvider(t *testing.T) {
+	f, err := Parse(FileName, []byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok := f.SetProvider(Provider{
+		Address:     "registry.terraform.io/hashicorp/aws",
+		Version:     "5.31.0",
+		Constraints: "~> 5.31",
+		Hashes:      []string{"zh:new", "h1:new"},
+	})
+	if !ok {
+		t.Fatal("SetProvider did not find the aws block")
+	}
+
+	want := `provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.31.0"
+  constraints = "~> 5.31"
+  hashes = [
+    "h1:new",
+    "zh:new",
+  ]
+}`
+	got := string(f.Bytes())
+	if !strings.Contains(got, want) {
+		t.Errorf("Unexpected lock file:\n%s", got)
+	}
+	if !strings.HasPrefix(got, "# This file is maintained automatically") {
+		t.Error("Header comment was lost")
+	}
+	if !strings.Contains(got, `"h1:random"`) {
+		t.Error("Unrelated provider block was modified")
+	}
+
+	if f.SetProvider(Provider{Address: "registry.terraform.io/hashicorp/google"}) {
+		t.Error("SetProvider reported success for a provider missing from the lock file")
+	}
+}
+
+func TestAddress(t *testing.T) {
+	tests := map[string]string{
+		"hashicorp/aws":                       "registry.terraform.io/hashicorp/aws",
+		"Integrations/GitHub":                 "registry.terraform.io/integrations/github",
+		"app.terraform.io/acme/internal":      "app.terraform.io/acme/internal",
+		"registry.opentofu.org/hashicorp/aws": "registry.opentofu.org/hashicorp/aws",
+	}
+	for source, want := range tests {
+		if got := Address(source); got != want {
+			t.Errorf("Address(%q) = %q, want %q", source, got, want)
+		}
+	}
+}
+
+func TestAddressOn(t *testing.T) {
+	if got := AddressOn("registry.opentofu.org", "hashicorp/aws"); got != "registry.opentofu.org/hashicorp/aws" {
+		t.Errorf("AddressOn() = %q", got)
+	}
+	if got := AddressOn("registry.opentofu.org", "app.terraform.io/acme/internal"); got != "app.terraform.io/acme/internal" {
+		t.Errorf("AddressOn() = %q, want the explicit host kept", got)
+	}
+}
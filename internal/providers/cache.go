@@ -0,0 +1,143 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CacheTTLEnv names the environment variable overriding DefaultCacheTTL,
+// as a Go duration such as 10m.
+const CacheTTLEnv = "TFINIT_CACHE_TTL"
+
+// DefaultCacheTTL is how long a cached registry response is used without
+// asking the registry again.
+const DefaultCacheTTL = time.Hour
+
+// Cache keeps registry JSON responses on disk, one file per URL. Entries
+// younger than TTL are used as they are; older ones are revalidated with
+// If-None-Match when the registry sent an ETag. Entries are replaced
+// atomically, so a Cache may be shared by goroutines and processes.
+type Cache struct {
+	Dir string
+	TTL time.Duration
+
+	// Refresh revalidates every entry regardless of its age.
+	Refresh bool
+}
+
+// cacheEntry is the on-disk form of a cached response.
+type cacheEntry struct {
+	URL     string          `json:"url"`
+	ETag    string          `json:"etag,omitempty"`
+	Fetched time.Time       `json:"fetched"`
+	Body    json.RawMessage `json:"body"`
+}
+
+// NewCache returns a cache in the tfinit/registry directory of the user
+// cache directory ($XDG_CACHE_HOME or ~/.cache on Linux), with the TTL of
+// CacheTTLEnv or DefaultCacheTTL. It returns nil when the platform has no
+// cache directory.
+func NewCache() *Cache {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil
+	}
+	ttl := DefaultCacheTTL
+	if d, err := time.ParseDuration(os.Getenv(CacheTTLEnv)); err == nil && d >= 0 {
+		ttl = d
+	}
+	return &Cache{Dir: filepath.Join(dir, "tfinit", "registry"), TTL: ttl}
+}
+
+func (c *Cache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// load returns the entry of url, or nil when there is none or it cannot
+// be read.
+func (c *Cache) load(url string) *cacheEntry {
+	content, err := os.ReadFile(c.path(url))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil || entry.URL != url {
+		return nil
+	}
+	return &entry
+}
+
+// store writes entry through a temporary file renamed into place, so
+// concurrent readers never see a partial entry.
+func (c *Cache) store(entry *cacheEntry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.Dir, ".entry-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path(entry.URL)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// cachedGet returns the body of url from the cache when it is fresh, and
+// from the registry otherwise. Failing to write the cache is not an error.
+func (c *Client) cachedGet(ctx context.Context, url string) ([]byte, error) {
+	entry := c.Cache.load(url)
+	if entry != nil && !c.Cache.Refresh && time.Since(entry.Fetched) < c.Cache.TTL {
+		return entry.Body, nil
+	}
+
+	etag := ""
+	if entry != nil {
+		etag = entry.ETag
+	}
+	resp, err := c.getIf(ctx, url, etag)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		entry.Fetched = time.Now()
+		c.Cache.store(entry)
+		return entry.Body, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.Cache.store(&cacheEntry{
+		URL:     url,
+		ETag:    resp.Header.Get("ETag"),
+		Fetched: time.Now(),
+		Body:    body,
+	})
+	return body, nil
+}
@@ -0,0 +1,118 @@
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newCachedRegistry serves the versions of hashicorp/aws with an ETag and
+// counts full responses and revalidations.
+func newCachedRegistry(t *testing.T) (*Client, *atomic.Int32, *atomic.Int32) {
+	t.Helper()
+	var full, revalidated atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidated.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"versions": [{"version": "5.1.0"}, {"version": "5.2.0"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := &Client{
+		BaseURL:    server.URL,
+		HTTPClient: server.Client(),
+		Cache:      &Cache{Dir: t.TempDir(), TTL: time.Hour},
+	}
+	return client, &full, &revalidated
+}
+
+func TestCache_Fresh(t *testing.T) {
+	client, full, revalidated := newCachedRegistry(t)
+
+	for i := 0; i < 3; i++ {
+		versions, err := client.ListVersions(t.Context(), "hashicorp/aws", false)
+		if err != nil {
+			t.Fatalf("ListVersions() error = %v", err)
+		}
+		if len(versions) != 2 {
+			t.Fatalf("ListVersions() returned %d versions, want 2", len(versions))
+		}
+	}
+	if full.Load() != 1 || revalidated.Load() != 0 {
+		t.Errorf("registry served %d full responses and %d revalidations, want 1 and 0", full.Load(), revalidated.Load())
+	}
+}
+
+func TestCache_Revalidate(t *testing.T) {
+	client, full, revalidated := newCachedRegistry(t)
+	client.Cache.TTL = 0
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListVersions(t.Context(), "hashicorp/aws", false); err != nil {
+			t.Fatalf("ListVersions() error = %v", err)
+		}
+	}
+	if full.Load() != 1 || revalidated.Load() != 1 {
+		t.Errorf("registry served %d full responses and %d revalidations, want 1 and 1", full.Load(), revalidated.Load())
+	}
+}
+
+func TestCache_Refresh(t *testing.T) {
+	client, _, revalidated := newCachedRegistry(t)
+
+	if _, err := client.ListVersions(t.Context(), "hashicorp/aws", false); err != nil {
+		t.Fatal(err)
+	}
+	client.Cache.Refresh = true
+	if _, err := client.ListVersions(t.Context(), "hashicorp/aws", false); err != nil {
+		t.Fatal(err)
+	}
+	if revalidated.Load() != 1 {
+		t.Errorf("Refresh revalidated %d times, want 1", revalidated.Load())
+	}
+}
+
+func TestCache_Concurrent(t *testing.T) {
+	client, _, _ := newCachedRegistry(t)
+	client.Cache.TTL = 0
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			versions, err := client.ListVersions(t.Context(), "hashicorp/aws", false)
+			if err == nil && len(versions) != 2 {
+				t.Errorf("ListVersions() returned %d versions, want 2", len(versions))
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("ListVersions() error = %v", err)
+		}
+	}
+}
+
+func TestNewCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv(CacheTTLEnv, "10m")
+
+	c := NewCache()
+	if c == nil || c.TTL != 10*time.Minute {
+		t.Errorf("NewCache() = %+v, want a TTL of 10m", c)
+	}
+}
@@ -1,10 +1,19 @@
 package providers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+
+	"warike/base/internal/flavor"
+	"warike/base/internal/semver"
 )
 
 const DefaultRegistryURL = "https://registry.terraform.io/v1/providers"
@@ -12,46 +21,277 @@ const DefaultRegistryURL = "https://registry.terraform.io/v1/providers"
 type Client struct {
 	BaseURL    string
 	HTTPClient *http.Client
+
+	// VersionsOnly is set for registries, like OpenTofu's, that serve the
+	// versions endpoint but no per-provider document. The latest version is
+	// then the newest stable entry of ListVersions.
+	VersionsOnly bool
+
+	// Credentials holds the tokens sent to registry hosts.
+	Credentials Credentials
+
+	// Mirror, a directory laid out like terraform providers mirror output or
+	// the URL of a network mirror, replaces the registry for version and
+	// package lookups. Sources without a hostname are looked up under the
+	// hostname of BaseURL.
+	Mirror string
+
+	// Releases, a JSON file or URL, replaces the public index of Terraform
+	// or OpenTofu releases read by CoreVersions.
+	Releases string
+
+	// Cache, when set, keeps registry responses between runs.
+	Cache *Cache
+
+	// MaxRetries is how often a request failing with a network error, 429
+	// or a 5xx status is retried, waiting RetryWait before the first retry
+	// and twice as long before each further one.
+	MaxRetries int
+	RetryWait  time.Duration
+
+	// MaxConcurrent limits the requests in flight at once, across all
+	// goroutines using the client. Zero means no limit.
+	MaxConcurrent int
+
+	// services caches the discovered service URLs of other hosts; slots
+	// holds the MaxConcurrent request slots.
+	mu       sync.Mutex
+	services map[string]map[string]string
+	slots    chan struct{}
 }
 
+// NewClient returns a client of the public Terraform registry using the
+// credentials of LoadCredentials, the mirror named by MirrorEnv, the
+// releases index named by ReleasesEnv and the cache of NewCache, retrying
+// failed requests. Unreadable credential files are ignored here; call
+// LoadCredentials to report them.
 func NewClient() *Client {
+	creds, _ := LoadCredentials()
 	return &Client{
 		BaseURL: DefaultRegistryURL,
 		HTTPClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		Credentials:   creds,
+		Mirror:        os.Getenv(MirrorEnv),
+		Releases:      os.Getenv(ReleasesEnv),
+		Cache:         NewCache(),
+		MaxRetries:    DefaultMaxRetries,
+		RetryWait:     DefaultRetryWait,
+		MaxConcurrent: DefaultMaxConcurrent,
 	}
 }
 
-func (c *Client) GetLatestVersion(source string) (string, error) {
-	// Construct URL. If BaseURL is the default, we append the source.
-	// If it's a test server (implied by not matching default), we might just hit the root 
-	// or append strictly if the test server expects it.
-	// For simplicity in this refactor, we just concatenate.
-	
-	// Handle trailing slash in BaseURL just in case
-	url := fmt.Sprintf("%s/%s", c.BaseURL, source)
-	// If fetching from a test server that doesn't handle paths, this might be tricky, 
-	// but httptest.Server handles paths fine. 
-	// In the test: BaseURL = server.URL. Code does: server.URL + "/" + source.
-	// The test handler needs to match anything or we don't care about the path in the test handler.
+// NewClientFor returns a client of the public registry of f.
+func NewClientFor(f flavor.Flavor) *Client {
+	c := NewClient()
+	if f.IsOpenTofu() {
+		c.BaseURL = f.RegistryURL()
+		c.VersionsOnly = true
+	}
+	return c
+}
 
-	resp, err := c.HTTPClient.Get(url)
+func (c *Client) GetLatestVersion(ctx context.Context, source string) (string, error) {
+	details, err := c.GetDetails(ctx, source)
+	return details.Version, err
+}
+
+// Details describes a provider as the registry lists it.
+type Details struct {
+	// Version is the latest release.
+	Version string
+	// Tier is official, partner or community; it and Description are
+	// empty when the registry does not say.
+	Tier        string
+	Description string
+}
+
+// GetDetails returns the latest version of source with the tier and
+// description the registry publishes for it.
+func (c *Client) GetDetails(ctx context.Context, source string) (Details, error) {
+	base, direct, err := c.providerURL(ctx, source)
 	if err != nil {
-		return "", err
+		return Details{}, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("bad status: %s", resp.Status)
+	// The per-provider document is not part of the registry protocol, so
+	// other registries and mirrors are only asked for their versions.
+	if c.VersionsOnly || !direct || c.Mirror != "" {
+		versions, err := c.ListVersions(ctx, source, false)
+		if err != nil {
+			return Details{}, err
+		}
+		if len(versions) == 0 {
+			return Details{}, fmt.Errorf("no releases of %s found", source)
+		}
+		return Details{Version: versions[len(versions)-1].Version.String()}, nil
 	}
 
 	var result struct {
-		Version string `json:"version"`
+		Version     string `json:"version"`
+		Tier        string `json:"tier"`
+		Description string `json:"description"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+	if err := c.getJSON(ctx, base, &result); err != nil {
+		return Details{}, err
 	}
 
-	return result.Version, nil
+	return Details{Version: result.Version, Tier: result.Tier, Description: result.Description}, nil
+}
+
+// Platform is an operating system and architecture pair a provider release
+// is built for.
+type Platform struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+}
+
+func (p Platform) String() string {
+	return p.OS + "_" + p.Arch
+}
+
+// ParsePlatform parses an "os_arch" pair such as "linux_amd64".
+func ParsePlatform(s string) (Platform, error) {
+	parts := strings.SplitN(s, "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Platform{}, fmt.Errorf("invalid platform %q: expected os_arch, e.g. linux_amd64", s)
+	}
+	return Platform{OS: parts[0], Arch: parts[1]}, nil
+}
+
+// CurrentPlatform returns the platform tfinit is running on.
+func CurrentPlatform() Platform {
+	return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// ProviderVersion is a single published release of a provider.
+type ProviderVersion struct {
+	Version semver.Version
+	// Protocols lists the plugin protocol versions the release supports,
+	// e.g. "5.0" or "6.0".
+	Protocols []string
+	Platforms []Platform
+}
+
+// ListVersions returns every release of source published in the registry,
+// sorted from oldest to newest. Prereleases are left out unless
+// includePrerelease is set; entries that are not valid versions are skipped.
+func (c *Client) ListVersions(ctx context.Context, source string, includePrerelease bool) ([]ProviderVersion, error) {
+	all, err := c.releases(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]ProviderVersion, 0, len(all))
+	for _, v := range all {
+		if v.Version.IsPrerelease() && !includePrerelease {
+			continue
+		}
+		versions = append(versions, v)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version.LessThan(versions[j].Version)
+	})
+
+	return versions, nil
 }
+
+// releases returns the releases of source, in registry order, from the
+// mirror when one is set and from the registry otherwise.
+func (c *Client) releases(ctx context.Context, source string) ([]ProviderVersion, error) {
+	if c.Mirror != "" {
+		return c.mirrorVersions(ctx, source)
+	}
+
+	base, _, err := c.providerURL(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Versions []struct {
+			Version   string     `json:"version"`
+			Protocols []string   `json:"protocols"`
+			Platforms []Platform `json:"platforms"`
+		} `json:"versions"`
+	}
+	if err := c.getJSON(ctx, base+"/versions", &result); err != nil {
+		return nil, err
+	}
+
+	versions := make([]ProviderVersion, 0, len(result.Versions))
+	for _, r := range result.Versions {
+		v, err := semver.Parse(r.Version)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, ProviderVersion{
+			Version:   v,
+			Protocols: r.Protocols,
+			Platforms: r.Platforms,
+		})
+	}
+	return versions, nil
+}
+
+// getJSON fetches url, through the cache when there is one, and decodes
+// the JSON response body into v.
+func (c *Client) getJSON(ctx context.Context, url string, v interface{}) error {
+	if c.Cache != nil {
+		body, err := c.cachedGet(ctx, url)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(body, v)
+	}
+
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// get requests url, authenticating with the token of its host, and fails
+// unless the response is 200 OK. The caller closes the body.
+func (c *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	return c.getIf(ctx, url, "")
+}
+
+// getIf is get with an If-None-Match header when etag is set, also
+// accepting 304 Not Modified then.
+func (c *Client) getIf(ctx context.Context, url, etag string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	token := c.Credentials.Token(req.URL.Host)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := c.do(req, func(status int) bool {
+		return status == http.StatusOK || etag != "" && status == http.StatusNotModified
+	})
+	if err != nil {
+		if resp != nil && token == "" && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+			return nil, fmt.Errorf("%w (no credentials for %s; set %s or run terraform login)", err, req.URL.Host, tokenEnvVar(req.URL.Hostname()))
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// tokenEnvVar is the TF_TOKEN_ variable holding the token of host.
+func tokenEnvVar(host string) string {
+	host = strings.ReplaceAll(host, "-", "__")
+	return "TF_TOKEN_" + strings.ReplaceAll(host, ".", "_")
+}
+
@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"warike/base/internal/flavor"
 )
 
 func TestClient_GetLatestVersion(t *testing.T) {
@@ -52,7 +54,7 @@ func TestClient_GetLatestVersion(t *testing.T) {
 			}
 
 			// We pass "source" but with our modified BaseURL, it effectively ignores the real registry
-			got, err := c.GetLatestVersion("hashicorp/aws")
+			got, err := c.GetLatestVersion(t.Context(), "hashicorp/aws")
 
 			if (err != nil) != tt.expectError {
 				t.Errorf("GetLatestVersion() error = %v, expectError %v", err, tt.expectError)
@@ -64,3 +66,127 @@ func TestClient_GetLatestVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_GetDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"version": "4.52.0", "tier": "partner", "description": "Cloudflare Terraform Provider"}`))
+	}))
+	defer server.Close()
+
+	c := &Client{BaseURL: server.URL, HTTPClient: server.Client()}
+	got, err := c.GetDetails(t.Context(), "cloudflare/cloudflare")
+	if err != nil {
+		t.Fatalf("GetDetails() error = %v", err)
+	}
+	want := Details{Version: "4.52.0", Tier: "partner", Description: "Cloudflare Terraform Provider"}
+	if got != want {
+		t.Errorf("GetDetails() = %+v, want %+v", got, want)
+	}
+}
+
+func TestClient_ListVersions(t *testing.T) {
+	response := `{"versions": [
+		{"version": "5.30.0", "protocols": ["5.0"], "platforms": [{"os": "linux", "arch": "amd64"}]},
+		{"version": "6.0.0-beta1", "protocols": ["5.0"], "platforms": []},
+		{"version": "5.4.0", "protocols": ["5.0"], "platforms": [{"os": "darwin", "arch": "arm64"}]},
+		{"version": "not-a-version", "protocols": [], "platforms": []},
+		{"version": "5.31.0", "protocols": ["5.0", "6.0"], "platforms": [{"os": "linux", "arch": "amd64"}]}
+	]}`
+
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	c := &Client{
+		BaseURL:    server.URL,
+		HTTPClient: server.Client(),
+	}
+
+	tests := []struct {
+		name              string
+		includePrerelease bool
+		want              []string
+	}{
+		{name: "Stable", includePrerelease: false, want: []string{"5.4.0", "5.30.0", "5.31.0"}},
+		{name: "WithPrerelease", includePrerelease: true, want: []string{"5.4.0", "5.30.0", "5.31.0", "6.0.0-beta1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.ListVersions(t.Context(), "hashicorp/aws", tt.includePrerelease)
+			if err != nil {
+				t.Fatalf("ListVersions() error = %v", err)
+			}
+			if gotPath != "/hashicorp/aws/versions" {
+				t.Errorf("ListVersions() requested %s", gotPath)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ListVersions() returned %d versions, want %d", len(got), len(tt.want))
+			}
+			for i, v := range tt.want {
+				if got[i].Version.String() != v {
+					t.Errorf("ListVersions()[%d] = %s, want %s", i, got[i].Version, v)
+				}
+			}
+		})
+	}
+
+	latest, _ := c.ListVersions(t.Context(), "hashicorp/aws", false)
+	last := latest[len(latest)-1]
+	if len(last.Protocols) != 2 || last.Protocols[1] != "6.0" {
+		t.Errorf("Protocols = %v, want [5.0 6.0]", last.Protocols)
+	}
+	if len(last.Platforms) != 1 || last.Platforms[0].String() != "linux_amd64" {
+		t.Errorf("Platforms = %v, want [linux_amd64]", last.Platforms)
+	}
+}
+
+func TestClient_ListVersions_BadStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	c := &Client{
+		BaseURL:    server.URL,
+		HTTPClient: server.Client(),
+	}
+
+	if _, err := c.ListVersions(t.Context(), "hashicorp/nope", false); err == nil {
+		t.Error("ListVersions() expected error for 404")
+	}
+}
+
+func TestClient_GetLatestVersion_VersionsOnly(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Write([]byte(`{"versions": [{"version": "5.31.0"}, {"version": "6.0.0-beta1"}, {"version": "5.4.0"}]}`))
+	}))
+	defer server.Close()
+
+	c := &Client{BaseURL: server.URL, HTTPClient: server.Client(), VersionsOnly: true}
+	got, err := c.GetLatestVersion(t.Context(), "hashicorp/aws")
+	if err != nil {
+		t.Fatalf("GetLatestVersion() error = %v", err)
+	}
+	if got != "5.31.0" {
+		t.Errorf("GetLatestVersion() = %v, want 5.31.0", got)
+	}
+	if gotPath != "/hashicorp/aws/versions" {
+		t.Errorf("Requested %s, want the versions endpoint", gotPath)
+	}
+}
+
+func TestNewClientFor(t *testing.T) {
+	c := NewClientFor(flavor.OpenTofu)
+	if c.BaseURL != "https://registry.opentofu.org/v1/providers" || !c.VersionsOnly {
+		t.Errorf("NewClientFor(tofu) = %+v", c)
+	}
+	if c := NewClientFor(flavor.Terraform); c.BaseURL != DefaultRegistryURL || c.VersionsOnly {
+		t.Errorf("NewClientFor(terraform) = %+v", c)
+	}
+}
@@ -0,0 +1,147 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+)
+
+// Credentials maps registry hostnames to API tokens.
+type Credentials map[string]string
+
+// Token returns the token for host, or "" when there is none.
+func (c Credentials) Token(host string) string {
+	return c[strings.ToLower(host)]
+}
+
+// LoadCredentials collects registry tokens the way Terraform does: from
+// credentials blocks of the CLI configuration file (TF_CLI_CONFIG_FILE or
+// ~/.terraformrc), from credentials.tfrc.json as written by terraform login,
+// and from TF_TOKEN_<host> environment variables, which take precedence.
+// Missing files are not an error.
+func LoadCredentials() (Credentials, error) {
+	rcPath, jsonPath := credentialsPaths()
+	return loadCredentials(rcPath, jsonPath, os.Environ())
+}
+
+// credentialsPaths returns the default CLI configuration and
+// credentials.tfrc.json paths of the current platform.
+func credentialsPaths() (rcPath, jsonPath string) {
+	configDir := ""
+	if runtime.GOOS == "windows" {
+		configDir = os.Getenv("APPDATA")
+		rcPath = filepath.Join(configDir, "terraform.rc")
+		jsonPath = filepath.Join(configDir, "terraform.d", "credentials.tfrc.json")
+	} else if home, err := os.UserHomeDir(); err == nil {
+		rcPath = filepath.Join(home, ".terraformrc")
+		jsonPath = filepath.Join(home, ".terraform.d", "credentials.tfrc.json")
+	}
+
+	if path := os.Getenv("TF_CLI_CONFIG_FILE"); path != "" {
+		rcPath = path
+	}
+	return rcPath, jsonPath
+}
+
+func loadCredentials(rcPath, jsonPath string, environ []string) (Credentials, error) {
+	creds := make(Credentials)
+
+	if rcPath != "" {
+		if err := creds.readCLIConfig(rcPath); err != nil {
+			return nil, err
+		}
+	}
+	if jsonPath != "" {
+		if err := creds.readJSON(jsonPath); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, kv := range environ {
+		name, token, ok := strings.Cut(kv, "=")
+		if !ok || token == "" {
+			continue
+		}
+		if host, ok := strings.CutPrefix(name, "TF_TOKEN_"); ok && host != "" {
+			creds[envHost(host)] = token
+		}
+	}
+	return creds, nil
+}
+
+// envHost decodes the hostname of a TF_TOKEN_ variable, where dots are
+// written as underscores and hyphens as double underscores.
+func envHost(s string) string {
+	s = strings.ReplaceAll(s, "__", "-")
+	s = strings.ReplaceAll(s, "_", ".")
+	return strings.ToLower(s)
+}
+
+// readCLIConfig reads the credentials blocks of a CLI configuration file:
+//
+//	credentials "app.terraform.io" {
+//	  token = "..."
+//	}
+func (c Credentials) readCLIConfig(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	file, diags := hclparse.NewParser().ParseHCLFile(path)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	var config struct {
+		Credentials []struct {
+			Host   string   `hcl:"host,label"`
+			Token  string   `hcl:"token,optional"`
+			Remain hcl.Body `hcl:",remain"`
+		} `hcl:"credentials,block"`
+		Remain hcl.Body `hcl:",remain"`
+	}
+	if diags := gohcl.DecodeBody(file.Body, nil, &config); diags.HasErrors() {
+		return diags
+	}
+
+	for _, block := range config.Credentials {
+		if block.Token != "" {
+			c[strings.ToLower(block.Host)] = block.Token
+		}
+	}
+	return nil
+}
+
+// readJSON reads a credentials.tfrc.json file.
+func (c Credentials) readJSON(path string) error {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var file struct {
+		Credentials map[string]struct {
+			Token string `json:"token"`
+		} `json:"credentials"`
+	}
+	if err := json.Unmarshal(content, &file); err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	for host, entry := range file.Credentials {
+		if entry.Token != "" {
+			c[strings.ToLower(host)] = entry.Token
+		}
+	}
+	return nil
+}
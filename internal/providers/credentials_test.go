@@ -0,0 +1,72 @@
+package providers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadCredentials(t *testing.T) {
+	dir := t.TempDir()
+	rcPath := filepath.Join(dir, ".terraformrc")
+	jsonPath := filepath.Join(dir, "credentials.tfrc.json")
+
+	rc := `plugin_cache_dir = "$HOME/.terraform.d/plugin-cache"
+
+credentials "app.terraform.io" {
+  token = "from-rc"
+}
+
+credentials "Registry.Example.com" {
+  token = "example-rc"
+}
+`
+	if err := os.WriteFile(rcPath, []byte(rc), 0600); err != nil {
+		t.Fatal(err)
+	}
+	js := `{"credentials": {"tfe.acme.io": {"token": "from-json"}, "app.terraform.io": {"token": "from-json"}}}`
+	if err := os.WriteFile(jsonPath, []byte(js), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := loadCredentials(rcPath, jsonPath, []string{
+		"TF_TOKEN_registry_example_com=from-env",
+		"TF_TOKEN_my__registry_acme_io=dashed",
+		"HOME=/home/user",
+	})
+	if err != nil {
+		t.Fatalf("loadCredentials() error = %v", err)
+	}
+
+	tests := map[string]string{
+		"app.terraform.io":      "from-json",
+		"tfe.acme.io":           "from-json",
+		"registry.example.com":  "from-env",
+		"my-registry.acme.io":   "dashed",
+		"APP.TERRAFORM.IO":      "from-json",
+		"registry.terraform.io": "",
+	}
+	for host, want := range tests {
+		if got := creds.Token(host); got != want {
+			t.Errorf("Token(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestLoadCredentials_MissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	creds, err := loadCredentials(filepath.Join(dir, "missing.rc"), filepath.Join(dir, "missing.json"), nil)
+	if err != nil || len(creds) != 0 {
+		t.Errorf("loadCredentials() = %v, %v, want no credentials", creds, err)
+	}
+}
+
+func TestLoadCredentials_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.tfrc.json")
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadCredentials("", path, nil); err == nil {
+		t.Error("loadCredentials() error = nil for invalid JSON")
+	}
+}
@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// discoveryPath is where registries publish their service URLs.
+const discoveryPath = "/.well-known/terraform.json"
+
+// splitSource splits a provider source into its hostname, empty when the
+// source has none, and its namespace/type path.
+func splitSource(source string) (host, name string, err error) {
+	parts := strings.Split(source, "/")
+	switch {
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return "", source, nil
+	case len(parts) == 3 && parts[0] != "" && parts[1] != "" && parts[2] != "":
+		return strings.ToLower(parts[0]), parts[1] + "/" + parts[2], nil
+	}
+	return "", "", fmt.Errorf("invalid provider source %q: expected [hostname/]namespace/type", source)
+}
+
+// providerURL returns the registry API URL of source. Sources without a
+// hostname, or with the hostname of BaseURL, are served by BaseURL; other
+// hosts are looked up through service discovery. direct reports the former.
+func (c *Client) providerURL(ctx context.Context, source string) (u string, direct bool, err error) {
+	host, name, err := splitSource(source)
+	if err != nil {
+		return "", false, err
+	}
+	if host == "" || host == c.baseHost() {
+		return c.BaseURL + "/" + name, true, nil
+	}
+
+	base, err := c.discover(ctx, host, "providers.v1")
+	if err != nil {
+		return "", false, err
+	}
+	return base + "/" + name, false, nil
+}
+
+func (c *Client) baseHost() string {
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// serviceKinds names the registries of the discovered services in errors.
+var serviceKinds = map[string]string{
+	"providers.v1": "provider",
+	"modules.v1":   "module",
+}
+
+// discover returns the base URL of service, such as providers.v1, on host,
+// without a trailing slash. The discovery document of a host is read once
+// for the lifetime of the client.
+func (c *Client) discover(ctx context.Context, host, service string) (string, error) {
+	c.mu.Lock()
+	services, ok := c.services[host]
+	c.mu.Unlock()
+
+	if !ok {
+		var err error
+		if services, err = c.readDiscovery(ctx, host); err != nil {
+			return "", err
+		}
+		c.mu.Lock()
+		if c.services == nil {
+			c.services = make(map[string]map[string]string)
+		}
+		c.services[host] = services
+		c.mu.Unlock()
+	}
+
+	base, ok := services[service]
+	if !ok {
+		return "", fmt.Errorf("%s does not host a %s registry", host, serviceKinds[service])
+	}
+	return base, nil
+}
+
+// readDiscovery fetches the discovery document of host and resolves its
+// service URLs. Services that are not URLs, such as login.v1, are dropped.
+func (c *Client) readDiscovery(ctx context.Context, host string) (map[string]string, error) {
+	doc, err := url.Parse("https://" + host + discoveryPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry host %q: %w", host, err)
+	}
+
+	var raw map[string]interface{}
+	if err := c.getJSON(ctx, doc.String(), &raw); err != nil {
+		return nil, fmt.Errorf("service discovery for %s: %w", host, err)
+	}
+
+	services := make(map[string]string)
+	for name, v := range raw {
+		ref, ok := v.(string)
+		if !ok {
+			continue
+		}
+		endpoint, err := doc.Parse(ref)
+		if err != nil {
+			continue
+		}
+		services[name] = strings.TrimSuffix(endpoint.String(), "/")
+	}
+	return services, nil
+}
@@ -0,0 +1,108 @@
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newPrivateRegistry serves service discovery and the versions endpoint of
+// acme/internal over TLS, requiring token. It returns the registry hostname
+// and a client trusting it.
+func newPrivateRegistry(t *testing.T, token string) (string, *Client, *int) {
+	t.Helper()
+	discoveries := 0
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		discoveries++
+		w.Write([]byte(`{"modules.v1": "/api/registry/v1/modules/", "providers.v1": "/api/registry/v1/providers/"}`))
+	})
+	mux.HandleFunc("/api/registry/v1/providers/acme/internal/versions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"versions": [{"version": "1.2.0"}, {"version": "1.10.0"}, {"version": "2.0.0-rc1"}]}`))
+	})
+	mux.HandleFunc("/api/registry/v1/modules/acme/network/aws/versions", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"modules": [{"versions": [{"version": "0.3.0"}]}]}`))
+	})
+	server := httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+
+	host := strings.TrimPrefix(server.URL, "https://")
+	client := &Client{
+		BaseURL:     DefaultRegistryURL,
+		HTTPClient:  server.Client(),
+		Credentials: Credentials{host: token},
+	}
+	return host, client, &discoveries
+}
+
+func TestSplitSource(t *testing.T) {
+	tests := []struct {
+		source, host, name string
+		wantErr            bool
+	}{
+		{"hashicorp/aws", "", "hashicorp/aws", false},
+		{"App.Terraform.io/acme/internal", "app.terraform.io", "acme/internal", false},
+		{"aws", "", "", true},
+		{"a/b/c/d", "", "", true},
+	}
+	for _, tt := range tests {
+		host, name, err := splitSource(tt.source)
+		if (err != nil) != tt.wantErr || host != tt.host || name != tt.name {
+			t.Errorf("splitSource(%q) = %q, %q, %v", tt.source, host, name, err)
+		}
+	}
+}
+
+func TestClient_PrivateRegistry(t *testing.T) {
+	host, client, discoveries := newPrivateRegistry(t, "secret")
+	source := host + "/acme/internal"
+
+	got, err := client.GetLatestVersion(t.Context(), source)
+	if err != nil {
+		t.Fatalf("GetLatestVersion() error = %v", err)
+	}
+	if got != "1.10.0" {
+		t.Errorf("GetLatestVersion() = %s, want 1.10.0", got)
+	}
+
+	versions, err := client.ListVersions(t.Context(), source, true)
+	if err != nil {
+		t.Fatalf("ListVersions() error = %v", err)
+	}
+	if len(versions) != 3 {
+		t.Errorf("ListVersions() returned %d versions, want 3", len(versions))
+	}
+
+	if *discoveries != 1 {
+		t.Errorf("Service discovery ran %d times, want 1", *discoveries)
+	}
+}
+
+func TestClient_PrivateRegistryWithoutToken(t *testing.T) {
+	host, client, _ := newPrivateRegistry(t, "secret")
+	client.Credentials = nil
+
+	_, err := client.ListVersions(t.Context(), host+"/acme/internal", false)
+	if err == nil || !strings.Contains(err.Error(), "TF_TOKEN_127_0_0_1") {
+		t.Errorf("ListVersions() error = %v, want a hint about TF_TOKEN_", err)
+	}
+}
+
+func TestClient_DiscoveryWithoutProviders(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"modules.v1": "/v1/modules/"}`))
+	}))
+	defer server.Close()
+
+	client := &Client{BaseURL: DefaultRegistryURL, HTTPClient: server.Client()}
+	host := strings.TrimPrefix(server.URL, "https://")
+	if _, err := client.ListVersions(t.Context(), host+"/acme/internal", false); err == nil || !strings.Contains(err.Error(), "does not host a provider registry") {
+		t.Errorf("ListVersions() error = %v", err)
+	}
+}
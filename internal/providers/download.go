@@ -0,0 +1,77 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Package describes a provider release archive for a single platform, as
+// returned by the registry download endpoint.
+type Package struct {
+	OS          string `json:"os"`
+	Arch        string `json:"arch"`
+	Filename    string `json:"filename"`
+	DownloadURL string `json:"download_url"`
+	SHASumsURL  string `json:"shasums_url"`
+	SHASum      string `json:"shasum"`
+
+	// Hashes are the lock file hashes of the package when they are known
+	// without downloading it, as for packages of a mirror.
+	Hashes []string `json:"-"`
+}
+
+// GetPackage looks up the archive of source at version for platform.
+func (c *Client) GetPackage(ctx context.Context, source, version string, platform Platform) (*Package, error) {
+	if c.Mirror != "" {
+		return c.mirrorPackage(ctx, source, version, platform)
+	}
+
+	base, _, err := c.providerURL(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/%s/download/%s/%s", base, version, platform.OS, platform.Arch)
+
+	var pkg Package
+	if err := c.getJSON(ctx, url, &pkg); err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// DownloadPackage saves the archive of pkg to a temporary file, verifies it
+// against the registry checksum and returns its path. The caller removes the
+// file when done.
+func (c *Client) DownloadPackage(ctx context.Context, pkg *Package) (string, error) {
+	resp, err := c.get(ctx, pkg.DownloadURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	f, err := os.CreateTemp("", "tfinit-provider-*.zip")
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), resp.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	if sum := hex.EncodeToString(h.Sum(nil)); pkg.SHASum != "" && sum != pkg.SHASum {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("checksum mismatch for %s: got %s, want %s", pkg.Filename, sum, pkg.SHASum)
+	}
+
+	return f.Name(), nil
+}
@@ -0,0 +1,65 @@
+package providers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestClient_DownloadPackage(t *testing.T) {
+	archive := []byte("not really a zip")
+	sum := sha256.Sum256(archive)
+	shasum := hex.EncodeToString(sum[:])
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/hashicorp/aws/5.31.0/download/linux/amd64", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"os": "linux", "arch": "amd64", "filename": "aws.zip",
+			"download_url": "%[1]s/files/aws.zip", "shasums_url": "%[1]s/files/SHA256SUMS", "shasum": %[2]q}`, server.URL, shasum)
+	})
+	mux.HandleFunc("/files/aws.zip", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	})
+
+	c := &Client{BaseURL: server.URL, HTTPClient: server.Client()}
+
+	pkg, err := c.GetPackage(t.Context(), "hashicorp/aws", "5.31.0", Platform{OS: "linux", Arch: "amd64"})
+	if err != nil {
+		t.Fatalf("GetPackage() error = %v", err)
+	}
+
+	path, err := c.DownloadPackage(t.Context(), pkg)
+	if err != nil {
+		t.Fatalf("DownloadPackage() error = %v", err)
+	}
+	defer os.Remove(path)
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(archive) {
+		t.Errorf("Downloaded %q, want %q", got, archive)
+	}
+
+	pkg.SHASum = "0000"
+	if _, err := c.DownloadPackage(t.Context(), pkg); err == nil {
+		t.Error("DownloadPackage() expected checksum mismatch error")
+	}
+}
+
+func TestParsePlatform(t *testing.T) {
+	p, err := ParsePlatform("darwin_arm64")
+	if err != nil || p.OS != "darwin" || p.Arch != "arm64" {
+		t.Errorf("ParsePlatform() = %+v, %v", p, err)
+	}
+	if _, err := ParsePlatform("linux"); err == nil {
+		t.Error("ParsePlatform() expected error for missing arch")
+	}
+}
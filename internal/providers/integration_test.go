@@ -11,7 +11,7 @@ func TestGetLatestVersion_RealRegistry(t *testing.T) {
 
 	client := NewClient()
 	// Using a very stable provider to ensure test reliability
-	version, err := client.GetLatestVersion("hashicorp/aws")
+	version, err := client.GetLatestVersion(t.Context(), "hashicorp/aws")
 	if err != nil {
 		t.Fatalf("Failed to fetch real version from Terraform Registry: %v", err)
 	}
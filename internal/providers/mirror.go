@@ -0,0 +1,267 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/sumdb/dirhash"
+
+	"warike/base/internal/semver"
+)
+
+// MirrorEnv names the environment variable selecting a provider mirror when
+// no mirror is given on the command line.
+const MirrorEnv = "TFINIT_PROVIDER_MIRROR"
+
+// isRemoteMirror reports whether the mirror is a network mirror URL rather
+// than a directory.
+func isRemoteMirror(mirror string) bool {
+	return strings.HasPrefix(mirror, "https://") || strings.HasPrefix(mirror, "http://")
+}
+
+// mirrorBase returns the directory or URL holding the releases of source in
+// the mirror: <mirror>/<hostname>/<namespace>/<type>.
+func (c *Client) mirrorBase(source string) (string, error) {
+	host, name, err := splitSource(source)
+	if err != nil {
+		return "", err
+	}
+	if host == "" {
+		host = c.baseHost()
+	}
+	if isRemoteMirror(c.Mirror) {
+		return strings.TrimSuffix(c.Mirror, "/") + "/" + host + "/" + name, nil
+	}
+	return filepath.Join(c.Mirror, host, filepath.FromSlash(name)), nil
+}
+
+// readMirrorJSON decodes the JSON document name below base. For directory
+// mirrors a missing document returns an error satisfying os.IsNotExist.
+func (c *Client) readMirrorJSON(ctx context.Context, base, name string, v interface{}) error {
+	if isRemoteMirror(c.Mirror) {
+		return c.getJSON(ctx, base+"/"+name, v)
+	}
+	content, err := os.ReadFile(filepath.Join(base, name))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("reading %s: %w", filepath.Join(base, name), err)
+	}
+	return nil
+}
+
+// mirrorArchive is a package of a release in a directory mirror: a zip
+// file (packed layout) or an extracted directory (unpacked layout).
+type mirrorArchive struct {
+	path string
+	dir  bool
+}
+
+// scanMirror lists the packages of a directory mirror without an index,
+// keyed by version and then platform. Packed packages are named
+// terraform-provider-<type>_<version>_<os>_<arch>.zip; unpacked packages
+// live in <version>/<os>_<arch>/.
+func scanMirror(base, typeName string) (map[string]map[string]mirrorArchive, error) {
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		return nil, err
+	}
+
+	releases := make(map[string]map[string]mirrorArchive)
+	add := func(version, platform string, a mirrorArchive) {
+		if releases[version] == nil {
+			releases[version] = make(map[string]mirrorArchive)
+		}
+		releases[version][platform] = a
+	}
+
+	prefix := "terraform-provider-" + typeName + "_"
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() {
+			rest, ok := strings.CutPrefix(name, prefix)
+			if !ok || !strings.HasSuffix(rest, ".zip") {
+				continue
+			}
+			// <version>_<os>_<arch>; versions contain no underscores.
+			parts := strings.SplitN(strings.TrimSuffix(rest, ".zip"), "_", 2)
+			if len(parts) == 2 {
+				add(parts[0], parts[1], mirrorArchive{path: filepath.Join(base, name)})
+			}
+			continue
+		}
+
+		if _, err := semver.Parse(name); err != nil {
+			continue
+		}
+		platforms, err := os.ReadDir(filepath.Join(base, name))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range platforms {
+			if p.IsDir() {
+				add(name, p.Name(), mirrorArchive{path: filepath.Join(base, name, p.Name()), dir: true})
+			}
+		}
+	}
+	return releases, nil
+}
+
+// mirrorVersions lists the releases of source in the mirror, using the
+// index.json of the network mirror protocol when present.
+func (c *Client) mirrorVersions(ctx context.Context, source string) ([]ProviderVersion, error) {
+	base, err := c.mirrorBase(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var index struct {
+		Versions map[string]json.RawMessage `json:"versions"`
+	}
+	err = c.readMirrorJSON(ctx, base, "index.json", &index)
+	if err == nil {
+		versions := make([]ProviderVersion, 0, len(index.Versions))
+		for raw := range index.Versions {
+			if v, err := semver.Parse(raw); err == nil {
+				versions = append(versions, ProviderVersion{Version: v})
+			}
+		}
+		return versions, nil
+	}
+	if isRemoteMirror(c.Mirror) || !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	releases, err := scanMirror(base, path.Base(filepath.ToSlash(base)))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s not found in mirror %s", source, c.Mirror)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]ProviderVersion, 0, len(releases))
+	for raw, archives := range releases {
+		v, err := semver.Parse(raw)
+		if err != nil {
+			continue
+		}
+		pv := ProviderVersion{Version: v}
+		for name := range archives {
+			if p, err := ParsePlatform(name); err == nil {
+				pv.Platforms = append(pv.Platforms, p)
+			}
+		}
+		sort.Slice(pv.Platforms, func(i, j int) bool { return pv.Platforms[i].String() < pv.Platforms[j].String() })
+		versions = append(versions, pv)
+	}
+	return versions, nil
+}
+
+// mirrorPackage looks up the package of source at version for platform in
+// the mirror. Packages of directory mirrors are hashed locally; network
+// mirrors provide the hashes in <version>.json.
+func (c *Client) mirrorPackage(ctx context.Context, source, version string, platform Platform) (*Package, error) {
+	base, err := c.mirrorBase(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var release struct {
+		Archives map[string]struct {
+			URL    string   `json:"url"`
+			Hashes []string `json:"hashes"`
+		} `json:"archives"`
+	}
+	err = c.readMirrorJSON(ctx, base, version+".json", &release)
+	if err != nil && (isRemoteMirror(c.Mirror) || !os.IsNotExist(err)) {
+		return nil, err
+	}
+
+	var archive mirrorArchive
+	if err == nil {
+		entry, ok := release.Archives[platform.String()]
+		if !ok {
+			return nil, fmt.Errorf("no %s package of %s %s in mirror %s", platform, source, version, c.Mirror)
+		}
+		if isRemoteMirror(c.Mirror) {
+			ref, err := url.Parse(base + "/" + version + ".json")
+			if err != nil {
+				return nil, err
+			}
+			download, err := ref.Parse(entry.URL)
+			if err != nil {
+				return nil, err
+			}
+			return &Package{
+				OS:          platform.OS,
+				Arch:        platform.Arch,
+				Filename:    path.Base(download.Path),
+				DownloadURL: download.String(),
+				Hashes:      entry.Hashes,
+			}, nil
+		}
+		archive = mirrorArchive{path: filepath.Join(base, filepath.FromSlash(entry.URL))}
+	} else {
+		releases, err := scanMirror(base, path.Base(filepath.ToSlash(base)))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		var ok bool
+		if archive, ok = releases[version][platform.String()]; !ok {
+			return nil, fmt.Errorf("no %s package of %s %s in mirror %s", platform, source, version, c.Mirror)
+		}
+	}
+
+	pkg := &Package{
+		OS:          platform.OS,
+		Arch:        platform.Arch,
+		Filename:    filepath.Base(archive.path),
+		DownloadURL: archive.path,
+	}
+	if archive.dir {
+		h1, err := dirhash.HashDir(archive.path, "", dirhash.Hash1)
+		if err != nil {
+			return nil, err
+		}
+		pkg.Hashes = []string{h1}
+		return pkg, nil
+	}
+
+	h1, err := dirhash.HashZip(archive.path, dirhash.Hash1)
+	if err != nil {
+		return nil, err
+	}
+	sum, err := fileSHA256(archive.path)
+	if err != nil {
+		return nil, err
+	}
+	pkg.SHASum = sum
+	pkg.Hashes = []string{h1, "zh:" + sum}
+	return pkg, nil
+}
+
+func fileSHA256(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,156 @@
+package providers
+
+import (
+	"archive/zip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeZip writes a provider package holding a single binary to path.
+func writeZip(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	entry, err := w.Create("terraform-provider-aws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.Write([]byte("binary"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMirror_Packed(t *testing.T) {
+	mirror := t.TempDir()
+	base := filepath.Join(mirror, "registry.terraform.io", "hashicorp", "aws")
+	writeZip(t, filepath.Join(base, "terraform-provider-aws_5.1.0_linux_amd64.zip"))
+	writeZip(t, filepath.Join(base, "terraform-provider-aws_5.1.0_darwin_arm64.zip"))
+	writeZip(t, filepath.Join(base, "terraform-provider-aws_5.10.0_linux_amd64.zip"))
+	writeZip(t, filepath.Join(base, "terraform-provider-aws_6.0.0-beta1_linux_amd64.zip"))
+
+	client := &Client{BaseURL: DefaultRegistryURL, Mirror: mirror}
+
+	latest, err := client.GetLatestVersion(t.Context(), "hashicorp/aws")
+	if err != nil {
+		t.Fatalf("GetLatestVersion() error = %v", err)
+	}
+	if latest != "5.10.0" {
+		t.Errorf("GetLatestVersion() = %s, want 5.10.0", latest)
+	}
+
+	versions, err := client.ListVersions(t.Context(), "registry.terraform.io/hashicorp/aws", false)
+	if err != nil {
+		t.Fatalf("ListVersions() error = %v", err)
+	}
+	if len(versions) != 2 || len(versions[0].Platforms) != 2 {
+		t.Errorf("ListVersions() = %+v, want 2 releases, the first on 2 platforms", versions)
+	}
+
+	pkg, err := client.GetPackage(t.Context(), "hashicorp/aws", "5.1.0", Platform{OS: "linux", Arch: "amd64"})
+	if err != nil {
+		t.Fatalf("GetPackage() error = %v", err)
+	}
+	if len(pkg.Hashes) != 2 || !strings.HasPrefix(pkg.Hashes[0], "h1:") || pkg.Hashes[1] != "zh:"+pkg.SHASum {
+		t.Errorf("GetPackage() hashes = %v, want h1: and zh: hashes", pkg.Hashes)
+	}
+
+	if _, err := client.GetPackage(t.Context(), "hashicorp/aws", "5.10.0", Platform{OS: "darwin", Arch: "arm64"}); err == nil {
+		t.Error("GetPackage() error = nil for a platform missing from the mirror")
+	}
+}
+
+func TestMirror_Unpacked(t *testing.T) {
+	mirror := t.TempDir()
+	dir := filepath.Join(mirror, "registry.terraform.io", "hashicorp", "aws", "5.1.0", "linux_amd64")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "terraform-provider-aws"), []byte("binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &Client{BaseURL: DefaultRegistryURL, Mirror: mirror}
+	pkg, err := client.GetPackage(t.Context(), "hashicorp/aws", "5.1.0", Platform{OS: "linux", Arch: "amd64"})
+	if err != nil {
+		t.Fatalf("GetPackage() error = %v", err)
+	}
+	if len(pkg.Hashes) != 1 || !strings.HasPrefix(pkg.Hashes[0], "h1:") {
+		t.Errorf("GetPackage() hashes = %v, want a single h1: hash", pkg.Hashes)
+	}
+}
+
+func TestMirror_Index(t *testing.T) {
+	mirror := t.TempDir()
+	base := filepath.Join(mirror, "registry.terraform.io", "hashicorp", "aws")
+	writeZip(t, filepath.Join(base, "archives", "aws.zip"))
+	files := map[string]string{
+		"index.json": `{"versions": {"5.1.0": {}, "5.2.0": {}}}`,
+		"5.2.0.json": `{"archives": {"linux_amd64": {"url": "archives/aws.zip"}}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(base, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := &Client{BaseURL: DefaultRegistryURL, Mirror: mirror}
+	latest, err := client.GetLatestVersion(t.Context(), "hashicorp/aws")
+	if err != nil || latest != "5.2.0" {
+		t.Errorf("GetLatestVersion() = %s, %v, want 5.2.0", latest, err)
+	}
+
+	pkg, err := client.GetPackage(t.Context(), "hashicorp/aws", "5.2.0", Platform{OS: "linux", Arch: "amd64"})
+	if err != nil {
+		t.Fatalf("GetPackage() error = %v", err)
+	}
+	if pkg.Filename != "aws.zip" || len(pkg.Hashes) != 2 {
+		t.Errorf("GetPackage() = %+v", pkg)
+	}
+}
+
+func TestMirror_Network(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/providers/registry.terraform.io/hashicorp/aws/index.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"versions": {"5.1.0": {}, "5.2.0": {}}}`))
+	})
+	mux.HandleFunc("/providers/registry.terraform.io/hashicorp/aws/5.2.0.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"archives": {"linux_amd64": {"url": "terraform-provider-aws_5.2.0_linux_amd64.zip", "hashes": ["h1:abc"]}}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := &Client{BaseURL: DefaultRegistryURL, HTTPClient: server.Client(), Mirror: server.URL + "/providers/"}
+	latest, err := client.GetLatestVersion(t.Context(), "hashicorp/aws")
+	if err != nil || latest != "5.2.0" {
+		t.Errorf("GetLatestVersion() = %s, %v, want 5.2.0", latest, err)
+	}
+
+	pkg, err := client.GetPackage(t.Context(), "hashicorp/aws", "5.2.0", Platform{OS: "linux", Arch: "amd64"})
+	if err != nil {
+		t.Fatalf("GetPackage() error = %v", err)
+	}
+	want := server.URL + "/providers/registry.terraform.io/hashicorp/aws/terraform-provider-aws_5.2.0_linux_amd64.zip"
+	if pkg.DownloadURL != want || len(pkg.Hashes) != 1 || pkg.Hashes[0] != "h1:abc" {
+		t.Errorf("GetPackage() = %+v, want %s with the mirror's hashes", pkg, want)
+	}
+}
+
+func TestMirror_MissingProvider(t *testing.T) {
+	client := &Client{BaseURL: DefaultRegistryURL, Mirror: t.TempDir()}
+	if _, err := client.ListVersions(t.Context(), "hashicorp/aws", false); err == nil || !strings.Contains(err.Error(), "not found in mirror") {
+		t.Errorf("ListVersions() error = %v", err)
+	}
+}
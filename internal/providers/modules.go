@@ -0,0 +1,90 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"warike/base/internal/semver"
+)
+
+// IsRegistryModule reports whether a module source is a registry address,
+// [hostname/]namespace/name/system, rather than a local path, a git URL or
+// another remote source.
+func IsRegistryModule(source string) bool {
+	if strings.HasPrefix(source, "github.com/") || strings.HasPrefix(source, "bitbucket.org/") ||
+		strings.Contains(source, "?") {
+		return false
+	}
+	parts := strings.Split(source, "/")
+	if len(parts) != 3 && len(parts) != 4 {
+		return false
+	}
+	for i, p := range parts {
+		if p == "" || p == "." || p == ".." {
+			return false
+		}
+		// Only the hostname may carry a colon, before its port.
+		if strings.Contains(p, ":") && (i > 0 || len(parts) == 3) {
+			return false
+		}
+	}
+	return true
+}
+
+// moduleURL returns the module registry API URL of source. The module
+// registry of BaseURL's host lives next to its provider registry, under
+// /v1/modules; other hosts are looked up through service discovery.
+func (c *Client) moduleURL(ctx context.Context, source string) (string, error) {
+	if !IsRegistryModule(source) {
+		return "", fmt.Errorf("invalid module source %q: expected [hostname/]namespace/name/system", source)
+	}
+
+	host, name := "", source
+	if parts := strings.SplitN(source, "/", 4); len(parts) == 4 {
+		host, name = strings.ToLower(parts[0]), strings.Join(parts[1:], "/")
+	}
+	if host == "" || host == c.baseHost() {
+		return strings.TrimSuffix(c.BaseURL, "/providers") + "/modules/" + name, nil
+	}
+
+	base, err := c.discover(ctx, host, "modules.v1")
+	if err != nil {
+		return "", err
+	}
+	return base + "/" + name, nil
+}
+
+// ListModuleVersions returns every release of the registry module source,
+// prereleases included, sorted from oldest to newest.
+func (c *Client) ListModuleVersions(ctx context.Context, source string) ([]semver.Version, error) {
+	base, err := c.moduleURL(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Modules []struct {
+			Versions []struct {
+				Version string `json:"version"`
+			} `json:"versions"`
+		} `json:"modules"`
+	}
+	if err := c.getJSON(ctx, base+"/versions", &result); err != nil {
+		return nil, err
+	}
+
+	var versions []semver.Version
+	for _, m := range result.Modules {
+		for _, r := range m.Versions {
+			if v, err := semver.Parse(r.Version); err == nil {
+				versions = append(versions, v)
+			}
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].LessThan(versions[j])
+	})
+	return versions, nil
+}
@@ -0,0 +1,62 @@
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsRegistryModule(t *testing.T) {
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{"terraform-aws-modules/vpc/aws", true},
+		{"app.terraform.io/acme/network/aws", true},
+		{"./modules/vpc", false},
+		{"../vpc", false},
+		{"github.com/acme/terraform-vpc", false},
+		{"git::https://example.com/vpc.git?ref=v1.0.0", false},
+		{"s3::https://bucket.s3.amazonaws.com/vpc.zip", false},
+		{"acme/vpc", false},
+	}
+	for _, tt := range tests {
+		if got := IsRegistryModule(tt.source); got != tt.want {
+			t.Errorf("IsRegistryModule(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestListModuleVersions(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/modules/terraform-aws-modules/vpc/aws/versions", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"modules": [{"versions": [{"version": "5.2.0"}, {"version": "5.10.0"}, {"version": "5.1.0"}]}]}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := &Client{BaseURL: server.URL + "/v1/providers", HTTPClient: server.Client()}
+	versions, err := client.ListModuleVersions(t.Context(), "terraform-aws-modules/vpc/aws")
+	if err != nil {
+		t.Fatalf("ListModuleVersions() error = %v", err)
+	}
+	if len(versions) != 3 || versions[0].String() != "5.1.0" || versions[2].String() != "5.10.0" {
+		t.Errorf("ListModuleVersions() = %v, want 5.1.0, 5.2.0 and 5.10.0 in order", versions)
+	}
+
+	if _, err := client.ListModuleVersions(t.Context(), "./modules/vpc"); err == nil {
+		t.Error("ListModuleVersions() error = nil for a local path")
+	}
+}
+
+func TestListModuleVersions_Discovery(t *testing.T) {
+	host, client, _ := newPrivateRegistry(t, "secret")
+
+	versions, err := client.ListModuleVersions(t.Context(), host+"/acme/network/aws")
+	if err != nil {
+		t.Fatalf("ListModuleVersions() error = %v", err)
+	}
+	if len(versions) != 1 || versions[0].String() != "0.3.0" {
+		t.Errorf("ListModuleVersions() = %v, want 0.3.0", versions)
+	}
+}
@@ -0,0 +1,88 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"warike/base/internal/flavor"
+	"warike/base/internal/semver"
+)
+
+// ReleasesEnv names the environment variable pointing at a releases index
+// to read instead of the public one, as a file path or URL.
+const ReleasesEnv = "TFINIT_RELEASES_INDEX"
+
+// releasesIndexURL is the public index of the Terraform or OpenTofu CLI
+// releases.
+func releasesIndexURL(f flavor.Flavor) string {
+	if f.IsOpenTofu() {
+		return "https://get.opentofu.org/tofu/api.json"
+	}
+	return "https://releases.hashicorp.com/terraform/index.json"
+}
+
+// CoreVersions returns the stable releases of the Terraform or OpenTofu
+// CLI, sorted from oldest to newest, from the Releases index or the public
+// index of f. Both the HashiCorp layout, {"versions": {"1.9.0": {...}}},
+// and the OpenTofu one, {"versions": [{"id": "1.8.0"}]}, are read.
+func (c *Client) CoreVersions(ctx context.Context, f flavor.Flavor) ([]semver.Version, error) {
+	index := c.Releases
+	if index == "" {
+		index = releasesIndexURL(f)
+	}
+
+	var doc struct {
+		Versions json.RawMessage `json:"versions"`
+	}
+	if isRemoteMirror(index) {
+		if err := c.getJSON(ctx, index, &doc); err != nil {
+			return nil, fmt.Errorf("reading releases index: %w", err)
+		}
+	} else {
+		content, err := os.ReadFile(index)
+		if err != nil {
+			return nil, fmt.Errorf("reading releases index: %w", err)
+		}
+		if err := json.Unmarshal(content, &doc); err != nil {
+			return nil, fmt.Errorf("reading releases index %s: %w", index, err)
+		}
+	}
+
+	var names []string
+	var keyed map[string]json.RawMessage
+	var listed []struct {
+		ID      string `json:"id"`
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(doc.Versions, &keyed); err == nil {
+		for name := range keyed {
+			names = append(names, name)
+		}
+	} else if err := json.Unmarshal(doc.Versions, &listed); err == nil {
+		for _, r := range listed {
+			if r.ID == "" {
+				r.ID = r.Version
+			}
+			names = append(names, r.ID)
+		}
+	} else {
+		return nil, fmt.Errorf("reading releases index %s: no versions list", index)
+	}
+
+	var versions []semver.Version
+	for _, name := range names {
+		if v, err := semver.Parse(name); err == nil && !v.IsPrerelease() {
+			versions = append(versions, v)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no %s releases found in %s", f.Command(), index)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].LessThan(versions[j])
+	})
+	return versions, nil
+}
@@ -0,0 +1,55 @@
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"warike/base/internal/flavor"
+)
+
+func TestCoreVersions_File(t *testing.T) {
+	index := filepath.Join(t.TempDir(), "index.json")
+	content := `{"name": "terraform", "versions": {"1.9.2": {}, "1.10.0-rc1": {}, "1.5.7": {}, "1.10.1": {}}}`
+	if err := os.WriteFile(index, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &Client{Releases: index}
+	versions, err := client.CoreVersions(t.Context(), flavor.Terraform)
+	if err != nil {
+		t.Fatalf("CoreVersions() error = %v", err)
+	}
+	if len(versions) != 3 || versions[0].String() != "1.5.7" || versions[2].String() != "1.10.1" {
+		t.Errorf("CoreVersions() = %v, want 1.5.7, 1.9.2 and 1.10.1", versions)
+	}
+}
+
+func TestCoreVersions_URL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"versions": [{"id": "1.8.0"}, {"id": "1.7.3"}, {"id": "1.9.0-beta1"}]}`))
+	}))
+	defer server.Close()
+
+	client := &Client{HTTPClient: server.Client(), Releases: server.URL + "/tofu/api.json"}
+	versions, err := client.CoreVersions(t.Context(), flavor.OpenTofu)
+	if err != nil {
+		t.Fatalf("CoreVersions() error = %v", err)
+	}
+	if len(versions) != 2 || versions[1].String() != "1.8.0" {
+		t.Errorf("CoreVersions() = %v, want 1.7.3 and 1.8.0", versions)
+	}
+}
+
+func TestCoreVersions_Invalid(t *testing.T) {
+	index := filepath.Join(t.TempDir(), "index.json")
+	if err := os.WriteFile(index, []byte(`{"versions": "1.9.0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	client := &Client{Releases: index}
+	if _, err := client.CoreVersions(t.Context(), flavor.Terraform); err == nil {
+		t.Error("CoreVersions() error = nil for an index without a versions list")
+	}
+}
@@ -0,0 +1,174 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is how often NewClient retries a failed request.
+	DefaultMaxRetries = 3
+	// DefaultRetryWait is the backoff before the first retry; it doubles on
+	// every further attempt.
+	DefaultRetryWait = 500 * time.Millisecond
+	// DefaultMaxConcurrent is the number of requests NewClient sends at once.
+	DefaultMaxConcurrent = 8
+
+	// maxRetryWait caps the exponential backoff.
+	maxRetryWait = 30 * time.Second
+	// maxRetryAfter is the longest Retry-After the client waits for; longer
+	// rate limits fail the request instead of stalling the run.
+	maxRetryAfter = time.Minute
+)
+
+// retryable reports whether a response with status may succeed when sent
+// again.
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the wait before retry attempt+1: RetryWait doubled per
+// attempt, capped at maxRetryWait, with jitter so that concurrent requests
+// do not retry in lockstep.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.RetryWait
+	for i := 0; i < attempt && d < maxRetryWait; i++ {
+		d *= 2
+	}
+	d = min(d, maxRetryWait)
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryAfter parses the Retry-After header of resp, given in seconds or as
+// an HTTP date. ok is false when there is none.
+func retryAfter(resp *http.Response) (d time.Duration, ok bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(value); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// acquire waits for a free request slot, returning the function giving it
+// back. Without MaxConcurrent requests are not limited.
+func (c *Client) acquire(ctx context.Context) (release func(), err error) {
+	if c.MaxConcurrent <= 0 {
+		return func() {}, nil
+	}
+
+	c.mu.Lock()
+	if c.slots == nil {
+		c.slots = make(chan struct{}, c.MaxConcurrent)
+	}
+	slots := c.slots
+	c.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// releaseBody gives back the request slot of a response once its body is
+// closed, so that slow downloads count against MaxConcurrent.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.release != nil {
+		b.release()
+		b.release = nil
+	}
+	return err
+}
+
+// do sends req, retrying network errors and retryable statuses up to
+// MaxRetries times. Retry-After is honored on 429 and 503 responses.
+// accept reports the statuses returned to the caller; others become errors.
+func (c *Client) do(req *http.Request, accept func(status int) bool) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		release, err := c.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil {
+			release()
+			if ctx.Err() != nil || attempt >= c.MaxRetries {
+				return nil, retriedError(attempt, err)
+			}
+			if err := sleep(ctx, c.backoff(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if accept(resp.StatusCode) {
+			resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+			return resp, nil
+		}
+		resp.Body.Close()
+		release()
+
+		if !retryable(resp.StatusCode) || attempt >= c.MaxRetries {
+			return resp, retriedError(attempt, fmt.Errorf("bad status: %s", resp.Status))
+		}
+		wait := c.backoff(attempt)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			if d, ok := retryAfter(resp); ok {
+				if d > maxRetryAfter {
+					return resp, fmt.Errorf("bad status: %s (retry after %s)", resp.Status, d.Round(time.Second))
+				}
+				wait = d
+			}
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retriedError notes the number of attempts in err when the request was
+// retried.
+func retriedError(attempt int, err error) error {
+	if attempt == 0 {
+		return err
+	}
+	return fmt.Errorf("%w (after %d attempts)", err, attempt+1)
+}
@@ -0,0 +1,173 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyRegistry fails the first failures requests with status, sending
+// retryAfter when set, and then serves two versions.
+func flakyRegistry(t *testing.T, failures int32, status int, retryAfter string) (*Client, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(`{"versions": [{"version": "5.1.0"}, {"version": "5.2.0"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return &Client{BaseURL: server.URL, HTTPClient: server.Client(), MaxRetries: 3, RetryWait: time.Millisecond}, &requests
+}
+
+func TestClient_Retries(t *testing.T) {
+	client, requests := flakyRegistry(t, 2, http.StatusBadGateway, "")
+
+	versions, err := client.ListVersions(t.Context(), "hashicorp/aws", false)
+	if err != nil {
+		t.Fatalf("ListVersions() error = %v", err)
+	}
+	if len(versions) != 2 || requests.Load() != 3 {
+		t.Errorf("ListVersions() = %d versions after %d requests, want 2 after 3", len(versions), requests.Load())
+	}
+}
+
+func TestClient_RetriesExhausted(t *testing.T) {
+	client, requests := flakyRegistry(t, 10, http.StatusServiceUnavailable, "")
+
+	_, err := client.ListVersions(t.Context(), "hashicorp/aws", false)
+	if err == nil || !strings.Contains(err.Error(), "503") || !strings.Contains(err.Error(), "after 4 attempts") {
+		t.Errorf("ListVersions() error = %v", err)
+	}
+	if requests.Load() != 4 {
+		t.Errorf("Registry got %d requests, want 4", requests.Load())
+	}
+}
+
+func TestClient_NoRetryOnClientError(t *testing.T) {
+	client, requests := flakyRegistry(t, 10, http.StatusNotFound, "")
+
+	if _, err := client.ListVersions(t.Context(), "hashicorp/aws", false); err == nil || err.Error() != "bad status: 404 Not Found" {
+		t.Errorf("ListVersions() error = %v", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("Registry got %d requests, want 1", requests.Load())
+	}
+}
+
+func TestClient_RetryAfter(t *testing.T) {
+	client, requests := flakyRegistry(t, 1, http.StatusTooManyRequests, "1")
+	client.RetryWait = time.Hour
+
+	start := time.Now()
+	if _, err := client.ListVersions(t.Context(), "hashicorp/aws", false); err != nil {
+		t.Fatalf("ListVersions() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 10*time.Second {
+		t.Errorf("ListVersions() took %s, want the 1s of Retry-After", elapsed)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Registry got %d requests, want 2", requests.Load())
+	}
+}
+
+func TestClient_RetryAfterTooLong(t *testing.T) {
+	client, requests := flakyRegistry(t, 1, http.StatusTooManyRequests, "3600")
+
+	_, err := client.ListVersions(t.Context(), "hashicorp/aws", false)
+	if err == nil || !strings.Contains(err.Error(), "retry after 1h0m0s") {
+		t.Errorf("ListVersions() error = %v", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("Registry got %d requests, want 1", requests.Load())
+	}
+}
+
+func TestClient_MaxConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.Write([]byte(`{"versions": [{"version": "5.1.0"}]}`))
+	}))
+	defer server.Close()
+
+	client := &Client{BaseURL: server.URL, HTTPClient: server.Client(), MaxConcurrent: 2}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.ListVersions(t.Context(), "hashicorp/aws", false); err != nil {
+				t.Errorf("ListVersions() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("%d requests were in flight at once, want at most 2", peak)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	client := &Client{RetryWait: 100 * time.Millisecond}
+	for attempt, base := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+		if d := client.backoff(attempt); d < base/2 || d > base {
+			t.Errorf("backoff(%d) = %s, want between %s and %s", attempt, d, base/2, base)
+		}
+	}
+	if d := client.backoff(20); d > maxRetryWait {
+		t.Errorf("backoff(20) = %s, want at most %s", d, maxRetryWait)
+	}
+}
+
+func TestRetryAfterDate(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", time.Now().Add(30*time.Second).UTC().Format(http.TimeFormat))
+
+	d, ok := retryAfter(resp)
+	if !ok || d <= 20*time.Second || d > 30*time.Second {
+		t.Errorf("retryAfter() = %s, %v, want about 30s", d, ok)
+	}
+}
+
+func TestClient_CanceledWhileWaiting(t *testing.T) {
+	client, _ := flakyRegistry(t, 10, http.StatusServiceUnavailable, "")
+	client.RetryWait = time.Hour
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.ListVersions(ctx, "hashicorp/aws", false)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ListVersions() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ListVersions() returned after %s, want right after the deadline", elapsed)
+	}
+}
@@ -0,0 +1,106 @@
+// Package report renders update results in machine-readable formats.
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"warike/base/internal/updater"
+)
+
+// Entry describes the update status of one provider requirement or module
+// call, as told by Kind.
+type Entry struct {
+	Kind          string `json:"kind,omitempty"`
+	Module        string `json:"module"`
+	File          string `json:"file"`
+	Line          int    `json:"line"`
+	Source        string `json:"source"`
+	Name          string `json:"name"`
+	OldConstraint string `json:"old_constraint"`
+	NewConstraint string `json:"new_constraint,omitempty"`
+	Latest        string `json:"latest"`
+	SkippedReason string `json:"skipped_reason,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// Report is the structured form of an update run.
+type Report struct {
+	DryRun  bool    `json:"dry_run"`
+	Entries []Entry `json:"providers"`
+}
+
+// New builds a report from update results. A requirement that was both
+// bumped and had a larger upgrade held back yields a single entry carrying
+// both the new constraint and the skipped reason.
+func New(results []updater.ModuleResult, dryRun bool) *Report {
+	r := &Report{DryRun: dryRun, Entries: []Entry{}}
+
+	type key struct {
+		kind updater.Kind
+		file string
+		name string
+	}
+	index := make(map[key]int)
+
+	entry := func(k key) *Entry {
+		if i, ok := index[k]; ok {
+			return &r.Entries[i]
+		}
+		r.Entries = append(r.Entries, Entry{})
+		index[k] = len(r.Entries) - 1
+		return &r.Entries[len(r.Entries)-1]
+	}
+
+	for _, res := range results {
+		for _, c := range res.Changes {
+			e := entry(key{c.Kind, c.File, c.Name})
+			e.Kind, e.Module, e.File, e.Line = string(c.Kind), c.Module, c.File, c.Line
+			e.Source, e.Name = c.Source, c.Name
+			e.OldConstraint, e.NewConstraint, e.Latest = c.From, c.To, c.Latest
+		}
+		for _, s := range res.Skipped {
+			e := entry(key{s.Kind, s.File, s.Name})
+			e.Kind, e.Module, e.File, e.Line = string(s.Kind), s.Module, s.File, s.Line
+			e.Source, e.Name = s.Source, s.Name
+			e.OldConstraint, e.Latest = s.Constraint, s.Latest
+			e.SkippedReason = s.Reason()
+		}
+		for _, f := range res.Failed {
+			name := f.Name
+			if name == "" {
+				name = f.Source
+			}
+			e := entry(key{f.Kind, f.File, name})
+			e.Kind, e.Module, e.File, e.Line = string(f.Kind), f.Module, f.File, f.Line
+			e.Source, e.Name = f.Source, f.Name
+			e.Error = f.Err.Error()
+		}
+	}
+
+	sort.SliceStable(r.Entries, func(i, j int) bool {
+		a, b := r.Entries[i], r.Entries[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+
+	for i := range r.Entries {
+		r.Entries[i].Module = filepath.ToSlash(filepath.Clean(r.Entries[i].Module))
+		r.Entries[i].File = filepath.ToSlash(filepath.Clean(r.Entries[i].File))
+	}
+
+	return r
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	// Constraints such as "~> 5.31" are written as they appear in the code.
+	enc.SetEscapeHTML(false)
+	return enc.Encode(r)
+}
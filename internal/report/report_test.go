@@ -0,0 +1,142 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"warike/base/internal/updater"
+)
+
+func sampleResults() []updater.ModuleResult {
+	return []updater.ModuleResult{{
+		Path: "live/prod",
+		Changes: []updater.Change{
+			{Module: "live/prod", File: "live/prod/versions.tf", Line: 5, Name: "aws", Source: "hashicorp/aws", From: "~> 5.30", To: "~> 5.31", Latest: "6.0.0"},
+		},
+		Skipped: []updater.Skip{
+			{Module: "live/prod", File: "live/prod/versions.tf", Line: 5, Name: "aws", Source: "hashicorp/aws", Constraint: "~> 5.30", Latest: "6.0.0", Policy: updater.AllowMinor, Major: true},
+		},
+	}}
+}
+
+func TestNew_MergesEntries(t *testing.T) {
+	r := New(sampleResults(), true)
+
+	if len(r.Entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(r.Entries))
+	}
+	e := r.Entries[0]
+	if e.NewConstraint != "~> 5.31" || e.OldConstraint != "~> 5.30" || e.Latest != "6.0.0" {
+		t.Errorf("Unexpected entry: %+v", e)
+	}
+	if e.SkippedReason != "major upgrade to 6.0.0 blocked by allow=minor" {
+		t.Errorf("SkippedReason = %q", e.SkippedReason)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := New(sampleResults(), false).WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct {
+		DryRun    bool `json:"dry_run"`
+		Providers []map[string]interface{}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(decoded.Providers) != 1 || decoded.Providers[0]["module"] != "live/prod" {
+		t.Errorf("Unexpected JSON: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"~> 5.31"`) {
+		t.Errorf("Expected constraints without HTML escaping: %s", buf.String())
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := New(sampleResults(), false).WriteSARIF(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid SARIF: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("Unexpected SARIF envelope: %s", buf.String())
+	}
+
+	results := log.Runs[0].Results
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].RuleID != RuleStaleProvider || results[1].RuleID != RuleHeldBack {
+		t.Errorf("Unexpected rules: %s, %s", results[0].RuleID, results[1].RuleID)
+	}
+	loc := results[0].Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "live/prod/versions.tf" || loc.Region.StartLine != 5 {
+		t.Errorf("Unexpected location: %+v", loc)
+	}
+	if !strings.Contains(buf.String(), "~> 5.31") {
+		t.Errorf("Expected constraints without HTML escaping: %s", buf.String())
+	}
+}
+
+func TestNew_Failures(t *testing.T) {
+	results := sampleResults()
+	results[0].Failed = []updater.Failure{
+		{Module: "live/prod", File: "live/prod/versions.tf", Line: 9, Name: "github", Source: "integrations/github", Err: errors.New("bad status: 503 Service Unavailable")},
+	}
+	r := New(results, false)
+
+	if len(r.Entries) != 2 || r.Entries[1].Error != "bad status: 503 Service Unavailable" {
+		t.Fatalf("Unexpected entries: %+v", r.Entries)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteSARIF(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid SARIF: %v", err)
+	}
+	last := log.Runs[0].Results[len(log.Runs[0].Results)-1]
+	if last.RuleID != RuleCheckFailed || last.Level != "error" {
+		t.Errorf("Unexpected result: %+v", last)
+	}
+}
+
+func TestNew_Modules(t *testing.T) {
+	results := sampleResults()
+	// A module sharing the local name of a provider gets its own entry.
+	results[0].Changes = append(results[0].Changes, updater.Change{
+		Kind: updater.KindModule, Module: "live/prod", File: "live/prod/versions.tf", Line: 12,
+		Name: "aws", Source: "terraform-aws-modules/vpc/aws", From: "5.1.0", To: "5.8.0", Latest: "5.8.0",
+	})
+	r := New(results, false)
+
+	last := r.Entries[len(r.Entries)-1]
+	if len(r.Entries) != 2 || last.Kind != "module" || last.NewConstraint != "5.8.0" {
+		t.Fatalf("Unexpected entries: %+v", r.Entries)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteSARIF(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid SARIF: %v", err)
+	}
+	found := log.Runs[0].Results[len(log.Runs[0].Results)-1]
+	if found.RuleID != RuleStaleModule {
+		t.Errorf("Unexpected result: %+v", found)
+	}
+}
@@ -0,0 +1,143 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"warike/base/internal/updater"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+
+	// RuleStaleProvider flags a constraint that excludes a newer release
+	// allowed by the update policy.
+	RuleStaleProvider = "tfinit/stale-provider"
+	// RuleStaleModule flags a module version or git ref older than a
+	// release allowed by the update policy.
+	RuleStaleModule = "tfinit/stale-module"
+	// RuleStaleRequiredVersion flags a required_version floor below the
+	// newest Terraform or OpenTofu release allowed by the update policy.
+	RuleStaleRequiredVersion = "tfinit/stale-required-version"
+	// RuleHeldBack flags a newer release the update policy did not allow.
+	RuleHeldBack = "tfinit/held-back-upgrade"
+	// RuleCheckFailed flags a provider or module whose releases could not be
+	// looked up.
+	RuleCheckFailed = "tfinit/check-failed"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           sarifRegion   `json:"region"`
+}
+
+type sarifArtifact struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// WriteSARIF writes the report as a SARIF 2.1.0 log so stale providers show
+// up as code scanning findings.
+func (r *Report) WriteSARIF(w io.Writer) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "tfinit",
+			InformationURI: "https://github.com/warike/terraform-files",
+			Rules: []sarifRule{
+				{ID: RuleStaleProvider, ShortDescription: sarifMessage{Text: "Provider version constraint is out of date"}},
+				{ID: RuleStaleModule, ShortDescription: sarifMessage{Text: "Module version is out of date"}},
+				{ID: RuleStaleRequiredVersion, ShortDescription: sarifMessage{Text: "required_version floor is out of date"}},
+				{ID: RuleHeldBack, ShortDescription: sarifMessage{Text: "Upgrade held back by update policy"}},
+				{ID: RuleCheckFailed, ShortDescription: sarifMessage{Text: "Provider or module versions could not be checked"}},
+			},
+		}},
+		Results: []sarifResult{},
+	}
+
+	for _, e := range r.Entries {
+		switch {
+		case e.NewConstraint == "":
+		case e.Kind == string(updater.KindModule):
+			run.Results = append(run.Results, sarifEntry(e, RuleStaleModule, "warning",
+				fmt.Sprintf("module %s (%s) is at %s; %s is available.", e.Name, e.Source, e.OldConstraint, e.NewConstraint)))
+		case e.Kind == string(updater.KindCore):
+			run.Results = append(run.Results, sarifEntry(e, RuleStaleRequiredVersion, "warning",
+				fmt.Sprintf("%s required_version is %q; %q raises the floor towards %s.", e.Source, e.OldConstraint, e.NewConstraint, e.Latest)))
+		default:
+			run.Results = append(run.Results, sarifEntry(e, RuleStaleProvider, "warning",
+				fmt.Sprintf("%s (%s) is constrained to %q; %q allows %s.", e.Source, e.Name, e.OldConstraint, e.NewConstraint, e.Latest)))
+		}
+		if e.SkippedReason != "" {
+			run.Results = append(run.Results, sarifEntry(e, RuleHeldBack, "note",
+				fmt.Sprintf("%s (%s): %s.", e.Source, e.Name, e.SkippedReason)))
+		}
+		if e.Error != "" {
+			run.Results = append(run.Results, sarifEntry(e, RuleCheckFailed, "error",
+				fmt.Sprintf("%s could not be checked: %s.", e.Source, e.Error)))
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	// Constraints such as "~> 5.31" are written as they appear in the code.
+	enc.SetEscapeHTML(false)
+	return enc.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}})
+}
+
+func sarifEntry(e Entry, rule, level, text string) sarifResult {
+	return sarifResult{
+		RuleID:  rule,
+		Level:   level,
+		Message: sarifMessage{Text: text},
+		Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifact{URI: e.File},
+			Region:           sarifRegion{StartLine: e.Line},
+		}}},
+	}
+}
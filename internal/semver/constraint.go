@@ -0,0 +1,233 @@
+package semver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constraint is a single "<op> <version>" term of a constraint string.
+type Constraint struct {
+	// Op is one of "=", "!=", ">", ">=", "<", "<=" or "~>". A bare version
+	// is reported as "=".
+	Op      string
+	Version Version
+
+	// start and end locate the version text within the original string.
+	start, end int
+}
+
+// Constraints is a parsed, comma separated list of constraint terms. All
+// terms must hold for a version to satisfy it.
+type Constraints struct {
+	raw   string
+	terms []Constraint
+}
+
+// operators is ordered so that two character operators match first.
+var operators = []string{"~>", ">=", "<=", "!=", ">", "<", "="}
+
+// ParseConstraints parses a Terraform version constraint string such as
+// "~> 5.0" or ">= 4.0, < 6.0".
+func ParseConstraints(s string) (Constraints, error) {
+	c := Constraints{raw: s}
+
+	offset := 0
+	for _, term := range strings.Split(s, ",") {
+		termStart := offset
+		offset += len(term) + 1
+
+		trimmed := strings.TrimLeft(term, " \t")
+		pos := termStart + len(term) - len(trimmed)
+
+		op := ""
+		for _, candidate := range operators {
+			if strings.HasPrefix(trimmed, candidate) {
+				op = candidate
+				break
+			}
+		}
+		rest := trimmed[len(op):]
+		versionText := strings.TrimLeft(rest, " \t")
+		pos += len(op) + len(rest) - len(versionText)
+		versionText = strings.TrimRight(versionText, " \t")
+
+		if versionText == "" {
+			return Constraints{}, fmt.Errorf("invalid version constraint %q: missing version", s)
+		}
+		v, err := Parse(versionText)
+		if err != nil {
+			return Constraints{}, fmt.Errorf("invalid version constraint %q: %w", s, err)
+		}
+		if op == "" {
+			op = "="
+		}
+
+		c.terms = append(c.terms, Constraint{Op: op, Version: v, start: pos, end: pos + len(versionText)})
+	}
+
+	return c, nil
+}
+
+// String returns the constraint exactly as it was written.
+func (c Constraints) String() string {
+	return c.raw
+}
+
+// Terms returns the individual constraint terms.
+func (c Constraints) Terms() []Constraint {
+	return c.terms
+}
+
+// Check reports whether v satisfies every term. Prerelease versions only
+// match when a term names a prerelease of the same release, following
+// Terraform's rules.
+func (c Constraints) Check(v Version) bool {
+	if v.IsPrerelease() {
+		explicit := false
+		for _, t := range c.terms {
+			if t.Version.IsPrerelease() && t.Version.Major == v.Major && t.Version.Minor == v.Minor && t.Version.Patch == v.Patch {
+				explicit = true
+			}
+		}
+		if !explicit {
+			return false
+		}
+	}
+
+	for _, t := range c.terms {
+		if !t.Check(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Check reports whether v satisfies this single term.
+func (t Constraint) Check(v Version) bool {
+	cmp := v.Compare(t.Version)
+	switch t.Op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "~>":
+		bound, ok := t.upperBound()
+		return cmp >= 0 && (!ok || v.LessThan(bound))
+	}
+	return false
+}
+
+// upperBound returns the exclusive limit of a pessimistic constraint:
+// "~> 5.30" allows anything below 6.0.0 and "~> 5.30.1" anything below
+// 5.31.0. Like Terraform, "~> 5" has no upper bound and ok is false.
+func (t Constraint) upperBound() (bound Version, ok bool) {
+	v := t.Version
+	switch v.Segments() {
+	case 1:
+		return Version{}, false
+	case 2:
+		return Version{Major: v.Major + 1}, true
+	default:
+		return Version{Major: v.Major, Minor: v.Minor + 1}, true
+	}
+}
+
+// Bump rewrites the constraint so that it targets latest while keeping its
+// style: pins move to latest, "~>" floors are raised at their original
+// precision and upper bounds that exclude latest are lifted. Lower bounds and
+// exclusions are left alone. The returned flag reports whether anything
+// changed.
+func (c Constraints) Bump(latest Version) (string, bool) {
+	out := c.raw
+	changed := false
+
+	// Replace from the end so earlier offsets stay valid.
+	for i := len(c.terms) - 1; i >= 0; i-- {
+		t := c.terms[i]
+
+		next, ok := t.bump(latest)
+		if !ok {
+			continue
+		}
+		out = out[:t.start] + next + out[t.end:]
+		changed = true
+	}
+
+	return out, changed
+}
+
+// RaiseFloor is Bump that also raises ">=" lower bounds to floor at their
+// original precision, so ">= 1.5" becomes ">= 1.9" for floor 1.9.2.
+func (c Constraints) RaiseFloor(floor Version) (string, bool) {
+	out := c.raw
+	changed := false
+
+	for i := len(c.terms) - 1; i >= 0; i-- {
+		t := c.terms[i]
+
+		next, ok := t.bump(floor)
+		if t.Op == ">=" {
+			next = floor.Format(t.Version.Segments())
+			ok = MustParse(next).Compare(t.Version) > 0
+		}
+		if !ok {
+			continue
+		}
+		out = out[:t.start] + next + out[t.end:]
+		changed = true
+	}
+
+	return out, changed
+}
+
+func (t Constraint) bump(latest Version) (string, bool) {
+	segments := t.Version.Segments()
+
+	switch t.Op {
+	case "=":
+		if latest.Equal(t.Version) {
+			return "", false
+		}
+		return latest.String(), true
+
+	case "~>":
+		next := latest.Format(segments)
+		if MustParse(next).Compare(t.Version) > 0 {
+			return next, true
+		}
+
+	case "<=":
+		if !t.Check(latest) {
+			return latest.String(), true
+		}
+
+	case "<":
+		if !t.Check(latest) {
+			return nextBound(t.Version, latest).Format(segments), true
+		}
+	}
+
+	return "", false
+}
+
+// nextBound lifts an exclusive upper bound above latest, stepping at the
+// same level as the original bound: "< 6.0" becomes the next major, "< 5.31"
+// the next minor and "< 5.30.4" the next patch.
+func nextBound(bound, latest Version) Version {
+	switch {
+	case bound.Patch != 0:
+		return Version{Major: latest.Major, Minor: latest.Minor, Patch: latest.Patch + 1}
+	case bound.Minor != 0:
+		return Version{Major: latest.Major, Minor: latest.Minor + 1}
+	default:
+		return Version{Major: latest.Major + 1}
+	}
+}
@@ -0,0 +1,153 @@
+package semver
+
+import "testing"
+
+func TestParseConstraints(t *testing.T) {
+	tests := []struct {
+		input       string
+		ops         []string
+		expectError bool
+	}{
+		{input: "5.30.0", ops: []string{"="}},
+		{input: "= 5.30.0", ops: []string{"="}},
+		{input: "!=5.30.0", ops: []string{"!="}},
+		{input: "~> 5.0", ops: []string{"~>"}},
+		{input: ">= 4.0, < 6.0", ops: []string{">=", "<"}},
+		{input: "> 1.0,<=2.0", ops: []string{">", "<="}},
+		{input: "", expectError: true},
+		{input: ">=", expectError: true},
+		{input: "~> five", expectError: true},
+		{input: ">= 4.0,", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			c, err := ParseConstraints(tt.input)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("ParseConstraints(%q) error = %v, expectError %v", tt.input, err, tt.expectError)
+			}
+			if tt.expectError {
+				return
+			}
+			terms := c.Terms()
+			if len(terms) != len(tt.ops) {
+				t.Fatalf("ParseConstraints(%q) returned %d terms, want %d", tt.input, len(terms), len(tt.ops))
+			}
+			for i, op := range tt.ops {
+				if terms[i].Op != op {
+					t.Errorf("term %d op = %q, want %q", i, terms[i].Op, op)
+				}
+			}
+			if c.String() != tt.input {
+				t.Errorf("String() = %q, want %q", c.String(), tt.input)
+			}
+		})
+	}
+}
+
+func TestConstraints_Check(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"5.30.0", "5.30.0", true},
+		{"5.30.0", "5.30.1", false},
+		{"!= 5.30.0", "5.30.1", true},
+		{"> 5.30.0", "5.30.0", false},
+		{">= 5.30.0", "5.30.0", true},
+		{"< 6.0", "5.99.0", true},
+		{"< 6.0", "6.0.0", false},
+		{"<= 6.0", "6.0.0", true},
+		{"~> 5.30", "5.99.0", true},
+		{"~> 5.30", "6.0.0", false},
+		{"~> 5.30", "5.29.0", false},
+		{"~> 5.30.1", "5.30.9", true},
+		{"~> 5.30.1", "5.31.0", false},
+		{"~> 5", "7.2.0", true},
+		{"~> 5", "4.9.0", false},
+		{">= 4.0, < 6.0", "5.1.0", true},
+		{">= 4.0, < 6.0", "6.1.0", false},
+		{">= 1.0", "2.0.0-beta1", false},
+		{"2.0.0-beta1", "2.0.0-beta1", true},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseConstraints(tt.constraint)
+		if err != nil {
+			t.Fatalf("ParseConstraints(%q) failed: %v", tt.constraint, err)
+		}
+		if got := c.Check(MustParse(tt.version)); got != tt.want {
+			t.Errorf("%q.Check(%s) = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestConstraints_Bump(t *testing.T) {
+	tests := []struct {
+		constraint string
+		latest     string
+		want       string
+		changed    bool
+	}{
+		{"5.30.0", "5.31.0", "5.31.0", true},
+		{"5.31.0", "5.31.0", "5.31.0", false},
+		{"= 5.30.0", "5.31.0", "= 5.31.0", true},
+		{"~> 5.30", "5.31.2", "~> 5.31", true},
+		{"~> 5.30", "5.30.4", "~> 5.30", false},
+		{"~> 5.30.0", "5.31.2", "~> 5.31.2", true},
+		{"~>5.0", "6.2.0", "~>6.2", true},
+		{">= 4.0", "6.2.0", ">= 4.0", false},
+		{">= 4.0, < 6.0", "5.9.0", ">= 4.0, < 6.0", false},
+		{">= 4.0, < 6.0", "6.2.0", ">= 4.0, < 7.0", true},
+		{">= 4.0,< 5.31", "5.31.1", ">= 4.0,< 5.32", true},
+		{"< 5.30.4", "5.30.6", "< 5.30.7", true},
+		{"<= 5.30", "5.31.1", "<= 5.31.1", true},
+		{"!= 5.30.1, ~> 5.29", "5.31.0", "!= 5.30.1, ~> 5.31", true},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseConstraints(tt.constraint)
+		if err != nil {
+			t.Fatalf("ParseConstraints(%q) failed: %v", tt.constraint, err)
+		}
+		got, changed := c.Bump(MustParse(tt.latest))
+		if changed != tt.changed {
+			t.Errorf("%q.Bump(%s) changed = %v, want %v", tt.constraint, tt.latest, changed, tt.changed)
+		}
+		if changed && got != tt.want {
+			t.Errorf("%q.Bump(%s) = %q, want %q", tt.constraint, tt.latest, got, tt.want)
+		}
+	}
+}
+
+func TestConstraints_RaiseFloor(t *testing.T) {
+	tests := []struct {
+		constraint string
+		floor      string
+		want       string
+		changed    bool
+	}{
+		{">= 1.5", "1.9.2", ">= 1.9", true},
+		{">= 1.5.0", "1.9.2", ">= 1.9.2", true},
+		{">= 1.9", "1.9.2", ">= 1.9", false},
+		{">= 1.5, < 2.0", "1.9.2", ">= 1.9, < 2.0", true},
+		{"~> 1.5", "1.9.2", "~> 1.9", true},
+		{"1.5.7", "1.9.2", "1.9.2", true},
+		{"> 1.5", "1.9.2", "> 1.5", false},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseConstraints(tt.constraint)
+		if err != nil {
+			t.Fatalf("ParseConstraints(%q) failed: %v", tt.constraint, err)
+		}
+		got, changed := c.RaiseFloor(MustParse(tt.floor))
+		if changed != tt.changed {
+			t.Errorf("%q.RaiseFloor(%s) changed = %v, want %v", tt.constraint, tt.floor, changed, tt.changed)
+		}
+		if changed && got != tt.want {
+			t.Errorf("%q.RaiseFloor(%s) = %q, want %q", tt.constraint, tt.floor, got, tt.want)
+		}
+	}
+}
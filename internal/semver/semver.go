@@ -0,0 +1,172 @@
+// Package semver implements the subset of semantic versioning used by
+// Terraform: version numbers and version constraint strings.
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version. Missing minor or patch components
+// are treated as zero, but the number of components written is remembered so
+// that rewritten constraints keep their original precision.
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Metadata   string
+
+	segments int
+}
+
+// Parse parses a version such as "5", "5.30", "5.30.0" or "1.0.0-beta1".
+func Parse(s string) (Version, error) {
+	var v Version
+	rest := strings.TrimPrefix(strings.TrimSpace(s), "v")
+
+	if i := strings.IndexByte(rest, '+'); i >= 0 {
+		v.Metadata = rest[i+1:]
+		rest = rest[:i]
+	}
+	if i := strings.IndexByte(rest, '-'); i >= 0 {
+		v.Prerelease = rest[i+1:]
+		rest = rest[:i]
+		if v.Prerelease == "" {
+			return Version{}, fmt.Errorf("invalid version %q: empty prerelease", s)
+		}
+	}
+
+	parts := strings.Split(rest, ".")
+	if len(parts) > 3 || rest == "" {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+
+	nums := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		nums[i] = n
+	}
+
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+	v.segments = len(parts)
+	return v, nil
+}
+
+// MustParse is like Parse but panics on invalid input.
+func MustParse(s string) Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Segments returns the number of numeric components the version was written
+// with.
+func (v Version) Segments() int {
+	if v.segments == 0 {
+		return 3
+	}
+	return v.segments
+}
+
+// IsPrerelease reports whether v carries a prerelease label.
+func (v Version) IsPrerelease() bool {
+	return v.Prerelease != ""
+}
+
+// Compare returns -1, 0 or 1 following semantic versioning precedence.
+// Build metadata is ignored.
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// LessThan reports whether v sorts before o.
+func (v Version) LessThan(o Version) bool {
+	return v.Compare(o) < 0
+}
+
+// Equal reports whether v and o have the same precedence.
+func (v Version) Equal(o Version) bool {
+	return v.Compare(o) == 0
+}
+
+// String returns the full version, always with three numeric components.
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Metadata != "" {
+		s += "+" + v.Metadata
+	}
+	return s
+}
+
+// Format returns the version truncated to the given number of numeric
+// components. Prerelease labels are only kept at full precision.
+func (v Version) Format(segments int) string {
+	switch segments {
+	case 1:
+		return strconv.Itoa(v.Major)
+	case 2:
+		return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+	default:
+		return v.String()
+	}
+}
+
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	ap, bp := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(ap) && i < len(bp); i++ {
+		an, aErr := strconv.Atoi(ap[i])
+		bn, bErr := strconv.Atoi(bp[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return sign(an - bn)
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(ap[i], bp[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return sign(len(ap) - len(bp))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,66 @@
+package semver
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input       string
+		want        string
+		segments    int
+		expectError bool
+	}{
+		{input: "5.30.0", want: "5.30.0", segments: 3},
+		{input: "5.30", want: "5.30.0", segments: 2},
+		{input: "5", want: "5.0.0", segments: 1},
+		{input: "v1.2.3", want: "1.2.3", segments: 3},
+		{input: "1.0.0-beta1", want: "1.0.0-beta1", segments: 3},
+		{input: "1.0.0+build.5", want: "1.0.0+build.5", segments: 3},
+		{input: "", expectError: true},
+		{input: "1.2.3.4", expectError: true},
+		{input: "1.x", expectError: true},
+		{input: "1.0.0-", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Parse(%q) error = %v, expectError %v", tt.input, err, tt.expectError)
+			}
+			if tt.expectError {
+				return
+			}
+			if got.String() != tt.want {
+				t.Errorf("Parse(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+			if got.Segments() != tt.segments {
+				t.Errorf("Parse(%q).Segments() = %d, want %d", tt.input, got.Segments(), tt.segments)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.0", "1.0.0", 0},
+		{"1.0.0", "1.0.1", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-rc.1", "1.0.0-beta.11", 1},
+		{"1.0.0+a", "1.0.0+b", 0},
+	}
+
+	for _, tt := range tests {
+		if got := MustParse(tt.a).Compare(MustParse(tt.b)); got != tt.want {
+			t.Errorf("Compare(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+)
+
+// Categories orders the catalog categories in the provider list, with their
+// headings. Other categories follow in alphabetical order.
+var Categories = []struct {
+	Name  string
+	Label string
+}{
+	{"cloud", "Cloud"},
+	{"saas", "SaaS"},
+	{"utility", "Utility"},
+}
+
+// categoryRank orders category: the known ones first, then the others.
+func categoryRank(category string) int {
+	for i, c := range Categories {
+		if c.Name == category {
+			return i
+		}
+	}
+	return len(Categories)
+}
+
+// categoryLabel returns the heading of category.
+func categoryLabel(category string) string {
+	for _, c := range Categories {
+		if c.Name == category {
+			return c.Label
+		}
+	}
+	if category == "" {
+		return "Other"
+	}
+	return strings.ToUpper(category[:1]) + category[1:]
+}
+
+// category returns the catalog category of p.
+func (p Provider) category() string {
+	return p.Definition.Category
+}
+
+// description returns the registry description of p, or its catalog one.
+func (p Provider) description() string {
+	if p.Description != "" {
+		return p.Description
+	}
+	return p.Definition.Description
+}
+
+// fuzzyMatch reports whether the runes of pattern appear in s in order,
+// ignoring case.
+func fuzzyMatch(pattern, s string) bool {
+	s = strings.ToLower(s)
+	for _, r := range strings.ToLower(pattern) {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(string(r)):]
+	}
+	return true
+}
+
+// matches reports whether p matches the filter by its name, source or
+// description.
+func (p Provider) matches(filter string) bool {
+	filter = strings.TrimSpace(filter)
+	return filter == "" || fuzzyMatch(filter, p.Name) || fuzzyMatch(filter, p.Source) || fuzzyMatch(filter, p.description())
+}
+
+// Visible returns the indexes of the providers matching the filter, grouped
+// by category and in catalog order within a category.
+func (m Model) Visible() []int {
+	var visible []int
+	for i, p := range m.Providers {
+		if p.matches(m.Filter) {
+			visible = append(visible, i)
+		}
+	}
+	sort.SliceStable(visible, func(i, j int) bool {
+		a, b := m.Providers[visible[i]].category(), m.Providers[visible[j]].category()
+		if ra, rb := categoryRank(a), categoryRank(b); ra != rb {
+			return ra < rb
+		}
+		return a < b
+	})
+	return visible
+}
+
+// moveCursor moves the cursor by delta among the visible providers. A
+// cursor on a provider the filter hides goes to the first visible one.
+func (m Model) moveCursor(delta int) Model {
+	visible := m.Visible()
+	if len(visible) == 0 {
+		return m
+	}
+	pos := -1
+	for i, idx := range visible {
+		if idx == m.Cursor {
+			pos = i
+		}
+	}
+	if pos < 0 {
+		m.Cursor = visible[0]
+		return m
+	}
+	pos = min(max(pos+delta, 0), len(visible)-1)
+	m.Cursor = visible[pos]
+	return m
+}
+
+// cursorVisible reports whether the provider under the cursor is shown.
+func (m Model) cursorVisible() bool {
+	for _, idx := range m.Visible() {
+		if idx == m.Cursor {
+			return true
+		}
+	}
+	return false
+}
+
+// selectVisible selects or deselects every provider the filter shows,
+// leaving out the ones whose version could not be looked up.
+func (m Model) selectVisible(selected bool) Model {
+	for _, idx := range m.Visible() {
+		if selected && m.Providers[idx].FetchError != "" {
+			continue
+		}
+		m.Selected[idx] = selected
+	}
+	return m
+}
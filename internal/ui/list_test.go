@@ -0,0 +1,255 @@
+package ui
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"warike/base/internal/catalog"
+	"warike/base/internal/providers"
+)
+
+func provider(name, category, description string) Provider {
+	return Provider{
+		Name:       name,
+		Source:     "hc/" + name,
+		Definition: catalog.Provider{Name: name, Category: category, Description: description},
+	}
+}
+
+func listModel(providers ...Provider) Model {
+	return Model{Providers: providers, Selected: make([]bool, len(providers))}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"", "aws", true},
+		{"aws", "aws", true},
+		{"cfl", "cloudflare", true},
+		{"CF", "cloudflare", true},
+		{"gh", "GitHub", true},
+		{"fc", "cloudflare", false},
+		{"awss", "aws", false},
+		{"kube", "k8s", false},
+	}
+	for _, tt := range tests {
+		if got := fuzzyMatch(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestProvider_Matches(t *testing.T) {
+	p := provider("datadog", "saas", "Monitoring and observability")
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{"", true},
+		{"  ", true},
+		{"ddog", true},
+		{"hc/", true},
+		{"observ", true},
+		{" dd ", true},
+		{"aws", false},
+	}
+	for _, tt := range tests {
+		if got := p.matches(tt.filter); got != tt.want {
+			t.Errorf("matches(%q) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	// The registry description takes over the catalog one.
+	p.Description = "Cloud-scale metrics"
+	if !p.matches("metrics") || p.matches("observ") {
+		t.Error("matches() does not use the registry description")
+	}
+}
+
+func TestModel_Visible(t *testing.T) {
+	m := listModel(
+		provider("random", "utility", ""),
+		provider("github", "saas", ""),
+		provider("aws", "cloud", ""),
+		provider("custom", "", ""),
+		provider("zebra", "zoo", ""),
+		provider("vercel", "saas", ""),
+		provider("google", "cloud", ""),
+		provider("acme", "internal", ""),
+	)
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"", []string{"aws", "google", "github", "vercel", "random", "custom", "acme", "zebra"}},
+		{"g", []string{"google", "github"}},
+		{"e", []string{"google", "vercel", "acme", "zebra"}},
+		{"nothing", nil},
+	}
+	for _, tt := range tests {
+		m.Filter = tt.filter
+		var got []string
+		for _, i := range m.Visible() {
+			got = append(got, m.Providers[i].Name)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Visible() with filter %q = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestModel_SelectVisible(t *testing.T) {
+	tests := []struct {
+		filter   string
+		selected bool
+		before   []bool
+		want     []bool
+	}{
+		{"", true, []bool{false, true, false, false}, []bool{true, true, true, true}},
+		{"g", true, []bool{false, false, false, false}, []bool{false, true, true, false}},
+		{"g", false, []bool{true, true, true, true}, []bool{true, false, false, true}},
+		{"nothing", true, []bool{false, true, false, false}, []bool{false, true, false, false}},
+	}
+	for _, tt := range tests {
+		m := listModel(
+			provider("aws", "cloud", ""),
+			provider("google", "cloud", ""),
+			provider("github", "saas", ""),
+			provider("random", "utility", ""),
+		)
+		m.Filter = tt.filter
+		copy(m.Selected, tt.before)
+		m = m.selectVisible(tt.selected)
+		if !slices.Equal(m.Selected, tt.want) {
+			t.Errorf("selectVisible(%v) with filter %q = %v, want %v", tt.selected, tt.filter, m.Selected, tt.want)
+		}
+	}
+}
+
+func TestModel_MoveCursor(t *testing.T) {
+	// Visible order: aws (0), google (2), github (1), random (3).
+	m := listModel(
+		provider("aws", "cloud", ""),
+		provider("github", "saas", ""),
+		provider("google", "cloud", ""),
+		provider("random", "utility", ""),
+	)
+	tests := []struct {
+		filter string
+		cursor int
+		delta  int
+		want   int
+	}{
+		{"", 0, 1, 2},
+		{"", 2, 1, 1},
+		{"", 3, 1, 3},
+		{"", 0, -1, 0},
+		{"", 0, 10, 3},
+		{"g", 0, 0, 2},
+		{"g", 3, 1, 2},
+		{"g", 2, 1, 1},
+		{"nothing", 3, 1, 3},
+	}
+	for _, tt := range tests {
+		m.Filter, m.Cursor = tt.filter, tt.cursor
+		if got := m.moveCursor(tt.delta).Cursor; got != tt.want {
+			t.Errorf("moveCursor(%d) from %d with filter %q = %d, want %d", tt.delta, tt.cursor, tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestModel_FilterShrinksList(t *testing.T) {
+	var ps []Provider
+	for i := range 30 {
+		ps = append(ps, provider(fmt.Sprintf("p%02d", i), "cloud", ""))
+	}
+	tests := []struct {
+		name   string
+		cursor int
+		filter string
+		key    rune
+		want   int
+	}{
+		{"cursor kept", 29, "2", '9', 29},
+		{"cursor hidden", 29, "1", '5', 15},
+		{"list fits", 20, "p0", '3', 3},
+		{"window kept", 25, "p", '2', 25},
+		{"window reset", 25, "p", '1', 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := listModel(ps...)
+			m.Height = 14
+			m.Cursor = tt.cursor
+			m.Filtering = true
+			m.Filter = tt.filter
+
+			next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{tt.key}})
+			m = next.(Model)
+			if m.Cursor != tt.want {
+				t.Fatalf("Cursor = %d after filtering %q, want %d", m.Cursor, m.Filter, tt.want)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(m.listView(), "\n"), "\n")
+			if rows := m.Height - 8; len(lines) > rows {
+				t.Errorf("listView() has %d lines, want at most %d", len(lines), rows)
+			}
+			cursorLine := -1
+			for i, line := range lines {
+				if strings.HasPrefix(line, ">") {
+					cursorLine = i
+				}
+			}
+			if cursorLine < 0 || !strings.Contains(lines[cursorLine], m.Providers[tt.want].Name) {
+				t.Errorf("listView() does not show the cursor on %s:\n%s", m.Providers[tt.want].Name, strings.Join(lines, "\n"))
+			}
+		})
+	}
+}
+
+func TestFetchVersions_MarksFailedRows(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hc/broken" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"version": "1.2.0"}`))
+	}))
+	defer server.Close()
+
+	client := &providers.Client{BaseURL: server.URL, HTTPClient: server.Client()}
+	list := []Provider{provider("aws", "cloud", ""), provider("broken", "cloud", "")}
+	got, err := FetchVersions(t.Context(), client, list)
+	if err == nil {
+		t.Error("FetchVersions() error = nil, want the failed lookup")
+	}
+	if got[0].LatestVersion != "1.2.0" || got[0].FetchError != "" {
+		t.Errorf("FetchVersions() aws = %+v, want version 1.2.0", got[0])
+	}
+	if got[1].FetchError == "" {
+		t.Errorf("FetchVersions() broken = %+v, want a FetchError", got[1])
+	}
+
+	// The list stays usable, but the failed row cannot be selected.
+	m := listModel(got...)
+	if !strings.Contains(m.listView(), "broken (latest: unavailable)") {
+		t.Errorf("listView() does not mark the failed row:\n%s", m.listView())
+	}
+	m = m.selectVisible(true)
+	if !slices.Equal(m.Selected, []bool{true, false}) {
+		t.Errorf("selectVisible(true) = %v, want the failed row left out", m.Selected)
+	}
+	m.Cursor = 1
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	if m = next.(Model); m.Selected[1] || m.Error != "" {
+		t.Errorf("Selecting the failed row: Selected = %v, Error = %q", m.Selected, m.Error)
+	}
+}
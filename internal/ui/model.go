@@ -1,14 +1,16 @@
 package ui
 
 import (
+	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"warike/base/internal/catalog"
+	"warike/base/internal/flavor"
 	"warike/base/internal/generator"
 	"warike/base/internal/providers"
 )
@@ -18,11 +20,23 @@ type Provider struct {
 	Source          string
 	LatestVersion   string
 	IsVersionLatest bool
+	Definition      catalog.Provider
+	Aliases         []generator.Alias
+
+	// Tier (official, partner or community) and Description are filled in
+	// from the registry with the latest version, when it publishes them.
+	Tier        string
+	Description string
+
+	// FetchError explains why the latest version could not be looked up.
+	// Such a provider cannot be selected.
+	FetchError string
 }
 
 type Model struct {
-	Providers      []Provider
-	Selected       []bool
+	Providers []Provider
+	Selected  []bool
+	// Cursor is the index in Providers of the provider under the cursor.
 	Cursor         int
 	Spinner        spinner.Model
 	Loading        bool
@@ -31,30 +45,102 @@ type Model struct {
 	FilesGenerated bool
 	Client         *providers.Client
 	TargetDir      string
+
+	// Ctx bounds the registry lookups of the model. Quitting calls Cancel,
+	// when set, which should stop the lookups still in flight.
+	Ctx    context.Context
+	Cancel context.CancelFunc
+
+	// TemplateDirs override or add to the built-in templates.
+	TemplateDirs []string
+
+	// Backend is the state backend of the project, and Environments and
+	// Layout its environments and how they are laid out. With ChooseBackend
+	// set, generating asks for the backend first.
+	Backend      *generator.Backend
+	Environments []string
+	Layout       string
+
+	// RequiredVersion is the required_version of the project, filled in
+	// with the provider versions; it stays empty when the releases index
+	// cannot be read.
+	RequiredVersion string
+
+	// Flavor, Encryption and TofuFiles are passed on to the generator.
+	Flavor          flavor.Flavor
+	Encryption      bool
+	TofuFiles       bool
+	ChooseBackend   bool
+	ChoosingBackend bool
+	BackendCursor   int
+
+	// EnteringAlias is set while an alias of the provider under the cursor
+	// is typed into AliasInput, as name[,setting=value...]. AliasError
+	// explains why the last one was rejected.
+	EnteringAlias bool
+	AliasInput    string
+	AliasError    string
+
+	// Filter narrows the provider list to fuzzy matches; it is typed in
+	// while Filtering. Height is the terminal height the list fits in.
+	Filter    string
+	Filtering bool
+	Height    int
 }
 
-func InitialModel(targetDir string) Model {
-	p := []Provider{
-		{Name: "aws", Source: "hashicorp/aws"},
-		{Name: "google", Source: "hashicorp/google"},
-		{Name: "azurerm", Source: "hashicorp/azurerm"},
-		{Name: "github", Source: "integrations/github"},
-		{Name: "vercel", Source: "vercel/vercel"},
-		{Name: "cloudflare", Source: "cloudflare/cloudflare"},
+// BackendChoice is an entry of the backend step.
+type BackendChoice struct {
+	Name  string
+	Label string
+}
+
+// BackendChoices are the backends offered by the backend step, by their
+// generator.ParseBackend name.
+var BackendChoices = []BackendChoice{
+	{"none", "none (local state)"},
+	{"s3", "s3 with S3-native locking"},
+	{"s3-dynamodb", "s3 with DynamoDB locking"},
+	{"gcs", "gcs"},
+	{"azurerm", "azurerm"},
+	{"cloud", "HCP Terraform (cloud block)"},
+	{"http", "http"},
+	{"local", "local, one state file per environment"},
+}
+
+// CatalogProviders returns the selectable providers of a catalog.
+func CatalogProviders(cat *catalog.Catalog) []Provider {
+	var list []Provider
+	for _, def := range cat.Providers() {
+		list = append(list, Provider{Name: def.Name, Source: def.Source, Definition: def})
 	}
+	return list
+}
+
+// InitialModel returns a model offering the embedded provider catalog.
+func InitialModel(ctx context.Context, targetDir string) Model {
+	return NewModel(ctx, targetDir, catalog.Embedded())
+}
+
+// NewModel returns a model offering every provider of cat, whose registry
+// lookups run under ctx.
+func NewModel(ctx context.Context, targetDir string, cat *catalog.Catalog) Model {
+	p := CatalogProviders(cat)
 
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = SpinnerStyle
 
-	return Model{
+	m := Model{
 		Providers: p,
 		Selected:  make([]bool, len(p)),
 		Spinner:   s,
 		Loading:   true,
 		Client:    providers.NewClient(),
 		TargetDir: targetDir,
+		Ctx:       ctx,
 	}
+	// Start at the top of the grouped list.
+	return m.moveCursor(0)
 }
 
 func (m Model) Init() tea.Cmd {
@@ -62,39 +148,94 @@ func (m Model) Init() tea.Cmd {
 }
 
 type versionsFetchedMsg struct {
-	providers []Provider
-	err       error
+	providers       []Provider
+	requiredVersion string
+	err             error
 }
 
 func (m Model) fetchAllVersions() tea.Cmd {
 	return func() tea.Msg {
-		var wg sync.WaitGroup
-		var mu sync.Mutex
-		updatedProviders := make([]Provider, len(m.Providers))
-		copy(updatedProviders, m.Providers)
-		var firstErr error
-
-		for i := range m.Providers {
-			wg.Add(1)
-			go func(i int) {
-				defer wg.Done()
-				version, err := m.Client.GetLatestVersion(updatedProviders[i].Source)
-				mu.Lock()
-				defer mu.Unlock()
-				if err != nil {
-					if firstErr == nil {
-						firstErr = fmt.Errorf("failed to fetch version for %s: %w", updatedProviders[i].Name, err)
-					}
-					return
-				}
-				updatedProviders[i].LatestVersion = version
-				updatedProviders[i].IsVersionLatest = true
-			}(i)
+		updatedProviders, err := FetchVersions(m.Ctx, m.Client, m.Providers)
+		if m.Ctx.Err() == nil {
+			// Failed lookups are marked on their rows; the others stay usable.
+			err = nil
 		}
+		// The project is usable without a required_version.
+		required, _ := FetchRequiredVersion(m.Ctx, m.Client, m.Flavor)
+		return versionsFetchedMsg{providers: updatedProviders, requiredVersion: required, err: err}
+	}
+}
 
-		wg.Wait()
-		return versionsFetchedMsg{providers: updatedProviders, err: firstErr}
+// FetchRequiredVersion returns the required_version of new projects, based
+// on the newest release of the CLI of f in the client's releases index. A
+// client reading a mirror without a releases index of its own stays off the
+// network and gets no required_version.
+func FetchRequiredVersion(ctx context.Context, client *providers.Client, f flavor.Flavor) (string, error) {
+	if client.Mirror != "" && client.Releases == "" {
+		return "", nil
+	}
+	versions, err := client.CoreVersions(ctx, f)
+	if err != nil {
+		return "", err
+	}
+	return generator.RequiredVersion(versions[len(versions)-1]), nil
+}
+
+// FetchVersions looks up the latest version, tier and description of every
+// provider concurrently.
+// It returns a copy of list with LatestVersion filled in, or FetchError for
+// the providers whose lookup failed, and the first error encountered, if
+// any. Canceling ctx stops every lookup.
+func FetchVersions(ctx context.Context, client *providers.Client, list []Provider) ([]Provider, error) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	updatedProviders := make([]Provider, len(list))
+	copy(updatedProviders, list)
+	var firstErr error
+
+	for i := range list {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			details, err := client.GetDetails(ctx, updatedProviders[i].Source)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				updatedProviders[i].FetchError = err.Error()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to fetch version for %s: %w", updatedProviders[i].Name, err)
+				}
+				return
+			}
+			updatedProviders[i].LatestVersion = details.Version
+			updatedProviders[i].IsVersionLatest = true
+			updatedProviders[i].Tier = details.Tier
+			updatedProviders[i].Description = details.Description
+		}(i)
+	}
+
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return updatedProviders, err
+	}
+	return updatedProviders, firstErr
+}
+
+// GeneratorData builds the generator input for the given providers.
+func GeneratorData(targetDir string, selected []Provider) generator.GeneratorData {
+	genData := generator.GeneratorData{
+		ProjectName: generator.ProjectName(targetDir),
+	}
+	for _, p := range selected {
+		genData.Providers = append(genData.Providers, generator.ProviderConfig{
+			Name:          p.Name,
+			Source:        p.Source,
+			LatestVersion: p.LatestVersion,
+			Definition:    p.Definition,
+			Aliases:       p.Aliases,
+		})
 	}
+	return genData
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -102,32 +243,62 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		if m.Loading || m.FilesGenerated {
 			if msg.String() == "ctrl+c" || msg.String() == "q" || msg.String() == "enter" {
-				return m, tea.Quit
+				return m.quit()
 			}
 			return m, nil
 		}
 
+		if m.ChoosingBackend {
+			return m.updateBackend(msg)
+		}
+		if m.EnteringAlias {
+			return m.updateAlias(msg)
+		}
+		if m.Filtering {
+			return m.updateFilter(msg)
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "q":
-			return m, tea.Quit
+			return m.quit()
 		case "up", "k":
-			if m.Cursor > 0 {
-				m.Cursor--
-			}
+			m = m.moveCursor(-1)
 		case "down", "j":
-			if m.Cursor < len(m.Providers)-1 {
-				m.Cursor++
-			}
+			m = m.moveCursor(1)
+		case "/":
+			m.Filtering = true
+		case "esc":
+			m.Filter = ""
+		case "+":
+			m = m.selectVisible(true)
+		case "-":
+			m = m.selectVisible(false)
 		case "enter", " ":
-			m.Selected[m.Cursor] = !m.Selected[m.Cursor]
+			if m.cursorVisible() && (m.Selected[m.Cursor] || m.Providers[m.Cursor].FetchError == "") {
+				m.Selected[m.Cursor] = !m.Selected[m.Cursor]
+			}
+		case "a":
+			if m.cursorVisible() && m.Providers[m.Cursor].FetchError == "" {
+				m.EnteringAlias = true
+				m.AliasInput = ""
+				m.AliasError = ""
+			}
+		case "d":
+			// Drop the last alias of the provider.
+			if aliases := m.Providers[m.Cursor].Aliases; len(aliases) > 0 && m.cursorVisible() {
+				m.Providers[m.Cursor].Aliases = aliases[:len(aliases)-1]
+			}
 		case "g", "G":
-			m.FilesGenerated = true
-			if err := m.generateFiles(); err != nil {
-				m.Error = err.Error()
+			if m.ChooseBackend {
+				m.ChoosingBackend = true
+				return m, nil
 			}
-			return m, nil
+			return m.generate(), nil
 		}
 
+	case tea.WindowSizeMsg:
+		m.Height = msg.Height
+
 	case spinner.TickMsg:
 		var cmd tea.Cmd
 		m.Spinner, cmd = m.Spinner.Update(msg)
@@ -137,6 +308,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.Loading = false
 		m.VersionsLoaded = true
 		m.Providers = msg.providers
+		m.RequiredVersion = msg.requiredVersion
 		if msg.err != nil {
 			m.Error = msg.err.Error()
 		}
@@ -145,6 +317,120 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// updateBackend handles keys of the backend step.
+func (m Model) updateBackend(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m.quit()
+	case "esc":
+		m.ChoosingBackend = false
+	case "up", "k":
+		if m.BackendCursor > 0 {
+			m.BackendCursor--
+		}
+	case "down", "j":
+		if m.BackendCursor < len(BackendChoices)-1 {
+			m.BackendCursor++
+		}
+	case "enter", " ", "g", "G":
+		backend, err := generator.ParseBackend(BackendChoices[m.BackendCursor].Name)
+		if err != nil {
+			m.Error = err.Error()
+			return m, nil
+		}
+		if backend != nil && m.Backend != nil {
+			// Keep settings given on the command line.
+			backend.Config = m.Backend.Config
+		}
+		m.Backend = backend
+		m.ChoosingBackend = false
+		return m.generate(), nil
+	}
+	return m, nil
+}
+
+// updateFilter handles keys while the filter is typed: they edit it, and
+// the cursor follows the first match. Enter keeps the filter, esc clears it.
+func (m Model) updateFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m.quit()
+	case tea.KeyEsc:
+		m.Filter = ""
+		m.Filtering = false
+	case tea.KeyEnter:
+		m.Filtering = false
+	case tea.KeyUp:
+		return m.moveCursor(-1), nil
+	case tea.KeyDown:
+		return m.moveCursor(1), nil
+	case tea.KeyBackspace:
+		if r := []rune(m.Filter); len(r) > 0 {
+			m.Filter = string(r[:len(r)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.Filter += string(msg.Runes)
+	default:
+		return m, nil
+	}
+	if !m.cursorVisible() {
+		m = m.moveCursor(0)
+	}
+	return m, nil
+}
+
+// updateAlias handles keys of the alias input. Entering an alias selects
+// its provider.
+func (m Model) updateAlias(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m.quit()
+	case tea.KeyEsc:
+		m.EnteringAlias = false
+	case tea.KeyBackspace:
+		if r := []rune(m.AliasInput); len(r) > 0 {
+			m.AliasInput = string(r[:len(r)-1])
+		}
+	case tea.KeyEnter:
+		p := &m.Providers[m.Cursor]
+		_, alias, err := generator.ParseAlias(p.Name + "." + m.AliasInput)
+		if err == nil {
+			err = alias.Check(p.Definition)
+		}
+		for _, a := range p.Aliases {
+			if err == nil && a.Name == alias.Name {
+				err = fmt.Errorf("provider %s: duplicate alias %q", p.Name, a.Name)
+			}
+		}
+		if err != nil {
+			m.AliasError = err.Error()
+			return m, nil
+		}
+		p.Aliases = append(append([]generator.Alias(nil), p.Aliases...), alias)
+		m.Selected[m.Cursor] = true
+		m.EnteringAlias = false
+	case tea.KeyRunes, tea.KeySpace:
+		m.AliasInput += string(msg.Runes)
+	}
+	return m, nil
+}
+
+// quit stops the registry lookups still running and ends the program.
+func (m Model) quit() (tea.Model, tea.Cmd) {
+	if m.Cancel != nil {
+		m.Cancel()
+	}
+	return m, tea.Quit
+}
+
+func (m Model) generate() Model {
+	m.FilesGenerated = true
+	if err := m.generateFiles(); err != nil {
+		m.Error = err.Error()
+	}
+	return m
+}
+
 func (m Model) View() string {
 	if m.Error != "" {
 		return ErrorStyle.Render(fmt.Sprintf("Error: %s\n\nPress Enter to exit.", m.Error))
@@ -158,14 +444,64 @@ func (m Model) View() string {
 		return fmt.Sprintf("%s Fetching latest provider versions...", m.Spinner.View())
 	}
 
+	if m.ChoosingBackend {
+		return m.backendView()
+	}
+	if m.EnteringAlias {
+		return m.aliasView()
+	}
+
 	var sb strings.Builder
 	sb.WriteString(TitleStyle.Render("Select Terraform Providers"))
 	sb.WriteString("\n\n")
 
-	for i, p := range m.Providers {
+	switch {
+	case m.Filtering:
+		sb.WriteString(fmt.Sprintf("/%s_\n\n", m.Filter))
+	case m.Filter != "":
+		sb.WriteString(fmt.Sprintf("/%s\n\n", m.Filter))
+	}
+
+	list := m.listView()
+	if m.cursorVisible() {
+		list = lipgloss.JoinHorizontal(lipgloss.Top, list, DetailStyle.Render(m.detailView()))
+	}
+	sb.WriteString(list)
+
+	if m.Filtering {
+		sb.WriteString(HelpStyle.Render("\n[type] filter | [enter] keep | [esc] clear\n"))
+	} else {
+		sb.WriteString(HelpStyle.Render("\n[space/enter] select | [/] filter | [+/-] all/none | [a] add alias | [d] drop alias | [g] generate | [q] quit\n"))
+	}
+
+	return sb.String()
+}
+
+// listView renders the providers the filter shows under their category
+// headings, scrolled to keep the cursor in view when the terminal is short.
+func (m Model) listView() string {
+	visible := m.Visible()
+	if len(visible) == 0 {
+		return UncheckedStyle.Render("No providers match.") + "\n"
+	}
+
+	var lines []string
+	cursorLine := 0
+	category := ""
+	for n, i := range visible {
+		p := m.Providers[i]
+		if c := p.category(); n == 0 || c != category {
+			category = c
+			if n > 0 {
+				lines = append(lines, "")
+			}
+			lines = append(lines, CategoryStyle.Render(categoryLabel(c)))
+		}
+
 		cursor := " "
 		if m.Cursor == i {
 			cursor = ">"
+			cursorLine = len(lines)
 		}
 
 		checked := " "
@@ -176,64 +512,105 @@ func (m Model) View() string {
 		}
 
 		versionInfo := fmt.Sprintf("latest: %s", p.LatestVersion)
-		sb.WriteString(fmt.Sprintf("%s [%s] %s (%s)\n", cursor, style.Render(checked), p.Name, versionInfo))
+		if p.FetchError != "" {
+			versionInfo = "latest: unavailable"
+		}
+		lines = append(lines, fmt.Sprintf("%s [%s] %s (%s)", cursor, style.Render(checked), p.Name, versionInfo))
+		for _, a := range p.Aliases {
+			lines = append(lines, fmt.Sprintf("      + alias %s", a))
+		}
 	}
 
-	sb.WriteString(HelpStyle.Render("\n[space/enter] select | [g] generate | [q] quit\n"))
+	// Leave room for the title, the filter and the help.
+	if rows := m.Height - 8; m.Height > 0 && rows > 0 && len(lines) > rows {
+		top := min(max(cursorLine-rows/2, 0), len(lines)-rows)
+		lines = lines[top : top+rows]
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
 
+// detailView describes the provider under the cursor.
+func (m Model) detailView() string {
+	p := m.Providers[m.Cursor]
+
+	var sb strings.Builder
+	sb.WriteString(TitleStyle.Render(p.Name) + "\n")
+	sb.WriteString(fmt.Sprintf("source:  %s\n", p.Source))
+	if p.FetchError != "" {
+		sb.WriteString(fmt.Sprintf("latest:  unavailable (%s)\n", p.FetchError))
+	} else {
+		sb.WriteString(fmt.Sprintf("latest:  %s\n", p.LatestVersion))
+	}
+	if p.Tier != "" {
+		sb.WriteString(fmt.Sprintf("tier:    %s\n", p.Tier))
+	}
+	if d := p.description(); d != "" {
+		sb.WriteString("\n" + d)
+	}
 	return sb.String()
 }
 
-func (m Model) generateFiles() error {
-	// Ensure target directory exists
-	if m.TargetDir != "" && m.TargetDir != "." {
-		if err := os.MkdirAll(m.TargetDir, 0755); err != nil {
-			return fmt.Errorf("failed to create directory %s: %w", m.TargetDir, err)
+func (m Model) backendView() string {
+	var sb strings.Builder
+	sb.WriteString(TitleStyle.Render("Select State Backend"))
+	sb.WriteString("\n\n")
+
+	for i, c := range BackendChoices {
+		cursor := " "
+		style := UncheckedStyle
+		if m.BackendCursor == i {
+			cursor = ">"
+			style = CheckedStyle
 		}
+		sb.WriteString(fmt.Sprintf("%s %s\n", cursor, style.Render(c.Label)))
 	}
 
-	genData := generator.GeneratorData{
-		ProjectName: "my_project",
+	sb.WriteString(HelpStyle.Render("\n[enter] generate | [esc] back | [q] quit\n"))
+
+	return sb.String()
+}
+
+func (m Model) aliasView() string {
+	p := m.Providers[m.Cursor]
+
+	var sb strings.Builder
+	sb.WriteString(TitleStyle.Render(fmt.Sprintf("Add %s Alias", p.Name)))
+	sb.WriteString("\n\n")
+
+	var settings []string
+	for _, s := range p.Definition.AliasSettings {
+		settings = append(settings, s.Name)
+	}
+	if len(settings) > 0 {
+		sb.WriteString(fmt.Sprintf("name[,setting=value...] with settings %s\n", strings.Join(settings, ", ")))
+	} else {
+		sb.WriteString("name\n")
 	}
-	
-	if m.TargetDir != "" && m.TargetDir != "." {
-		genData.ProjectName = filepath.Base(m.TargetDir)
+	sb.WriteString(fmt.Sprintf("> %s_\n", m.AliasInput))
+	if m.AliasError != "" {
+		sb.WriteString(ErrorStyle.Render(m.AliasError) + "\n")
 	}
 
+	sb.WriteString(HelpStyle.Render("\n[enter] add | [esc] back\n"))
+
+	return sb.String()
+}
+
+func (m Model) generateFiles() error {
+	var selected []Provider
 	for i, p := range m.Providers {
 		if m.Selected[i] {
-			genData.Providers = append(genData.Providers, generator.ProviderConfig{
-				Name:          p.Name,
-				Source:        p.Source,
-				LatestVersion: p.LatestVersion,
-			})
+			selected = append(selected, p)
 		}
 	}
 
-	files := []struct {
-		name string
-		gen  func(generator.GeneratorData) ([]byte, error)
-	}{
-		{"provider.tf", generator.GenerateProviderFile},
-		{"variables.tf", generator.GenerateVariablesFile},
-		{"terraform.tfvars", generator.GenerateTfvarsFile},
-		{"main.tf", generator.GenerateMainFile},
-	}
-
-	for _, f := range files {
-		content, err := f.gen(genData)
-		if err != nil {
-			return err
-		}
-		
-		path := f.name
-		if m.TargetDir != "" {
-			path = filepath.Join(m.TargetDir, f.name)
-		}
-		
-		if err := generator.WriteFile(path, content); err != nil {
-			return err
-		}
-	}
-	return nil
+	data := GeneratorData(m.TargetDir, selected)
+	data.Backend = m.Backend
+	data.Environments = m.Environments
+	data.Layout = m.Layout
+	data.Flavor = m.Flavor
+	data.Encryption = m.Encryption
+	data.TofuFiles = m.TofuFiles
+	data.RequiredVersion = m.RequiredVersion
+	return generator.WriteProject(m.TargetDir, data, m.TemplateDirs...)
 }
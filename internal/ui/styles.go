@@ -11,4 +11,9 @@ var (
 	SpinnerStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("69"))
 	ErrorStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
 	SuccessStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("46"))
+	DiffAddStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	DiffDelStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	DiffHunkStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("69"))
+	CategoryStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("69"))
+	DetailStyle    = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("241")).Padding(0, 1).MarginLeft(2).Width(44)
 )
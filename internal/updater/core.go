@@ -0,0 +1,234 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"warike/base/internal/flavor"
+	"warike/base/internal/semver"
+)
+
+// coreRequirement is the required_version constraint of a terraform block.
+type coreRequirement struct {
+	Version string
+
+	versionRange hcl.Range
+}
+
+// parseRequiredVersions returns the required_version constraints of the
+// terraform blocks of src in source order.
+func parseRequiredVersions(filename string, src []byte) ([]coreRequirement, error) {
+	file, diags := hclsyntax.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("%s: unexpected body type %T", filename, file.Body)
+	}
+
+	var found []coreRequirement
+	for _, block := range body.Blocks {
+		if block.Type != "terraform" {
+			continue
+		}
+		attr, ok := block.Body.Attributes["required_version"]
+		if !ok {
+			continue
+		}
+		v, err := stringValue(attr.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: required_version: %w", attr.Expr.Range(), err)
+		}
+		found = append(found, coreRequirement{Version: v, versionRange: attr.Expr.Range()})
+	}
+	return found, nil
+}
+
+// Oldest releases speaking each plugin protocol. Terraform dropped protocol
+// 4 in 0.12; every OpenTofu release speaks protocols 5 and 6.
+var (
+	terraformProtocol5 = semver.MustParse("0.12.0")
+	terraformProtocol6 = semver.MustParse("1.0.0")
+)
+
+// supportsProtocols reports whether core, a release of the CLI of f, can
+// run a provider release speaking protocols. Releases that list no
+// protocols are assumed to run anywhere.
+func supportsProtocols(f flavor.Flavor, core semver.Version, protocols []string) bool {
+	if len(protocols) == 0 {
+		return true
+	}
+	for _, p := range protocols {
+		major, _, _ := strings.Cut(p, ".")
+		switch major {
+		case "4":
+			if !f.IsOpenTofu() && core.LessThan(terraformProtocol5) {
+				return true
+			}
+		case "5":
+			if f.IsOpenTofu() || !core.LessThan(terraformProtocol5) {
+				return true
+			}
+		case "6":
+			if f.IsOpenTofu() || !core.LessThan(terraformProtocol6) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// coreVersions returns the releases of the CLI of u.Flavor sorted
+// ascending, querying the releases index only once.
+func (u *Updater) coreVersions(ctx context.Context) ([]semver.Version, error) {
+	l := u.lookup("core", func() lookup {
+		versions, err := u.Client.CoreVersions(ctx, u.Flavor)
+		return lookup{versions: versions, err: err}
+	})
+	return l.versions, l.err
+}
+
+// runnable returns the releases of source among versions that core can
+// run, judged by the protocols the registry lists for them.
+func (u *Updater) runnable(source string, versions []semver.Version, core semver.Version) []semver.Version {
+	u.mu.Lock()
+	protocols := u.versions[source].protocols
+	u.mu.Unlock()
+
+	var out []semver.Version
+	for _, v := range versions {
+		if supportsProtocols(u.Flavor, core, protocols[v.String()]) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// updateRequiredVersions raises the floors of the required_version
+// constraints of files to the newest release the update policy allows,
+// recording changes, skips and failures in result. It returns the edits of
+// each file and the oldest release every constraint allows afterwards,
+// which ok is false for when the module sets no required_version or the
+// releases could not be looked up.
+func (u *Updater) updateRequiredVersions(ctx context.Context, files []*moduleFile, result *ModuleResult) (edits map[string][]versionEdit, floor semver.Version, ok bool, err error) {
+	var first *moduleFile
+	for _, f := range files {
+		if len(f.RequiredVersions) > 0 {
+			first = f
+			break
+		}
+	}
+	if first == nil {
+		return nil, semver.Version{}, false, nil
+	}
+
+	command := u.Flavor.Command()
+	versions, err := u.coreVersions(ctx)
+	if ctx.Err() != nil {
+		return nil, semver.Version{}, false, ctx.Err()
+	}
+	if err != nil {
+		result.Failed = append(result.Failed, Failure{
+			Kind:   KindCore,
+			Module: result.Path,
+			File:   first.Path,
+			Line:   first.RequiredVersions[0].versionRange.Start.Line,
+			Name:   "required_version",
+			Source: command,
+			Err:    err,
+		})
+		return nil, semver.Version{}, false, nil
+	}
+
+	edits = make(map[string][]versionEdit)
+	policy := u.policyFor(command, "required_version")
+	var all []semver.Constraints
+	for _, f := range files {
+		for _, r := range f.RequiredVersions {
+			line := r.versionRange.Start.Line
+			parsed, err := semver.ParseConstraints(r.Version)
+			if err != nil {
+				return nil, semver.Version{}, false, fmt.Errorf("%s: required_version: %w", f.Path, err)
+			}
+
+			lowest, found := oldestAllowed(versions, parsed)
+			if !found {
+				all = append(all, parsed)
+				continue
+			}
+			target, newest := lowest, lowest
+			for _, v := range versions {
+				if v.LessThan(lowest) || !parsed.Check(v) {
+					continue
+				}
+				newest = v
+				if policy.allows(lowest, v) {
+					target = v
+				}
+			}
+
+			if target.LessThan(newest) {
+				result.Skipped = append(result.Skipped, Skip{
+					Kind:       KindCore,
+					Module:     result.Path,
+					File:       f.Path,
+					Line:       line,
+					Name:       "required_version",
+					Source:     command,
+					Constraint: r.Version,
+					Latest:     newest.String(),
+					Policy:     policy,
+					Major:      newest.Major > target.Major,
+				})
+			}
+
+			next, raised := parsed.RaiseFloor(target)
+			if raised {
+				result.Changes = append(result.Changes, Change{
+					Kind:   KindCore,
+					Module: result.Path,
+					File:   f.Path,
+					Line:   line,
+					Name:   "required_version",
+					Source: command,
+					From:   r.Version,
+					To:     next,
+					Latest: newest.String(),
+				})
+				edits[f.Path] = append(edits[f.Path], versionEdit{Range: r.versionRange, Version: next})
+				if parsed, err = semver.ParseConstraints(next); err != nil {
+					return nil, semver.Version{}, false, err
+				}
+			}
+			all = append(all, parsed)
+		}
+	}
+
+	for _, v := range versions {
+		allowed := true
+		for _, c := range all {
+			allowed = allowed && c.Check(v)
+		}
+		if allowed {
+			return edits, v, true, nil
+		}
+	}
+	return edits, semver.Version{}, false, nil
+}
+
+// oldestAllowed returns the oldest of versions, sorted ascending, that
+// satisfies constraints.
+func oldestAllowed(versions []semver.Version, constraints semver.Constraints) (semver.Version, bool) {
+	for _, v := range versions {
+		if constraints.Check(v) {
+			return v, true
+		}
+	}
+	return semver.Version{}, false
+}
@@ -0,0 +1,125 @@
+package updater
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"warike/base/internal/flavor"
+	"warike/base/internal/providers"
+	"warike/base/internal/semver"
+)
+
+func TestSupportsProtocols(t *testing.T) {
+	tests := []struct {
+		flavor    flavor.Flavor
+		core      string
+		protocols []string
+		want      bool
+	}{
+		{flavor.Terraform, "0.11.14", []string{"4.0"}, true},
+		{flavor.Terraform, "0.12.0", []string{"4.0"}, false},
+		{flavor.Terraform, "0.15.5", []string{"5.0"}, true},
+		{flavor.Terraform, "0.15.5", []string{"6.0"}, false},
+		{flavor.Terraform, "1.0.0", []string{"6.0"}, true},
+		{flavor.Terraform, "0.13.0", []string{"5.0", "6.0"}, true},
+		{flavor.Terraform, "0.13.0", nil, true},
+		{flavor.OpenTofu, "1.6.0", []string{"6.0"}, true},
+	}
+	for _, tt := range tests {
+		if got := supportsProtocols(tt.flavor, semver.MustParse(tt.core), tt.protocols); got != tt.want {
+			t.Errorf("supportsProtocols(%s, %s, %v) = %v, want %v", tt.flavor, tt.core, tt.protocols, got, tt.want)
+		}
+	}
+}
+
+// newCoreUpdater serves hashicorp/aws 5.0.0 on protocol 5 and 5.1.0 on
+// protocol 6 only, with a releases index of Terraform 0.14.0 to 1.9.2.
+func newCoreUpdater(t *testing.T) *Updater {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"versions": [{"version": "5.0.0", "protocols": ["5.0"]}, {"version": "5.1.0", "protocols": ["6.0"]}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	index := filepath.Join(t.TempDir(), "index.json")
+	content := `{"versions": {"0.14.0": {}, "0.15.5": {}, "1.5.0": {}, "1.9.2": {}}}`
+	if err := os.WriteFile(index, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	u := NewUpdater()
+	u.Client = &providers.Client{BaseURL: server.URL, HTTPClient: server.Client(), Releases: index}
+	u.RequiredVersion = true
+	return u
+}
+
+const coreModule = `terraform {
+  required_version = ">= 0.14"
+
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+`
+
+func TestUpdateProject_RequiredVersion(t *testing.T) {
+	u := newCoreUpdater(t)
+
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "versions.tf")
+	if err := os.WriteFile(filePath, []byte(coreModule), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := u.UpdateProject(t.Context(), tmpDir)
+	if err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	if len(result.Changes) != 2 || result.Changes[0].Kind != KindCore || result.Changes[0].To != ">= 1.9" {
+		t.Fatalf("Changes = %v, want required_version and aws updated", result.Changes)
+	}
+
+	got, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.NewReplacer(`">= 0.14"`, `">= 1.9"`, `"~> 5.0"`, `"~> 5.1"`).Replace(coreModule)
+	if string(got) != want {
+		t.Errorf("Unexpected rewrite:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUpdateProject_RequiredVersionHoldsBackProtocol(t *testing.T) {
+	u := newCoreUpdater(t)
+	u.Policy = AllowMinor
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "versions.tf"), []byte(coreModule), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := u.UpdateProject(t.Context(), tmpDir)
+	if err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	// The floor only moves within 0.x, which cannot run protocol 6.
+	if len(result.Changes) != 1 || result.Changes[0].To != ">= 0.15" {
+		t.Fatalf("Changes = %v, want required_version raised to >= 0.15 only", result.Changes)
+	}
+	var held *Skip
+	for i, s := range result.Skipped {
+		if s.Source == "hashicorp/aws" {
+			held = &result.Skipped[i]
+		}
+	}
+	if held == nil || held.Latest != "5.1.0" || held.Core != "terraform 0.15.5" {
+		t.Errorf("Skipped = %v, want aws 5.1.0 held back by terraform 0.15.5", result.Skipped)
+	}
+}
@@ -0,0 +1,178 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/sumdb/dirhash"
+
+	"warike/base/internal/lockfile"
+	"warike/base/internal/providers"
+	"warike/base/internal/semver"
+)
+
+// syncLockFile brings the dependency lock file of dirName in line with the
+// updated constraints. constraints maps each changed provider source to the
+// constraints the module now declares for it. Providers missing from the
+// lock file are left for terraform init to add. It returns nil when the
+// module has no lock file or nothing in it changed. Providers whose new
+// version or hashes cannot be determined keep their entry and are returned
+// as failures. A dry run only reports the new version and constraints: it
+// never downloads packages, so the entry keeps its hashes.
+func (u *Updater) syncLockFile(ctx context.Context, dirName string, constraints map[string][]string) (*FileChange, []Failure, error) {
+	path := filepath.Join(dirName, lockfile.FileName)
+	before, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lf, err := lockfile.Parse(path, before)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sources := make([]string, 0, len(constraints))
+	for source := range constraints {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	var failed []Failure
+	fail := func(source string, err error) {
+		failed = append(failed, Failure{Kind: KindProvider, Module: dirName, File: path, Source: source, Err: err})
+	}
+
+	for _, source := range sources {
+		locked, ok, err := lf.Provider(lockfile.AddressOn(u.Flavor.RegistryHost(), source))
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		version, err := u.lockVersion(ctx, source, constraints[source])
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		if err != nil {
+			fail(source, err)
+			continue
+		}
+
+		next := lockfile.Provider{
+			Address:     locked.Address,
+			Version:     version,
+			Constraints: strings.Join(constraints[source], ", "),
+			Hashes:      locked.Hashes,
+		}
+		if next.Version != locked.Version && !u.DryRun {
+			next.Hashes, err = u.packageHashes(ctx, source, version)
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			if err != nil {
+				fail(source, fmt.Errorf("failed to fetch hashes for %s: %w", version, err))
+				continue
+			}
+		}
+		lf.SetProvider(next)
+	}
+
+	after := lf.Bytes()
+	if string(after) == string(before) {
+		return nil, failed, nil
+	}
+	return &FileChange{Path: path, Before: before, After: after}, failed, nil
+}
+
+// lockVersion returns the newest release satisfying every constraint, which
+// is the version terraform init would lock.
+func (u *Updater) lockVersion(ctx context.Context, source string, raw []string) (string, error) {
+	var all []semver.Constraints
+	for _, r := range raw {
+		c, err := semver.ParseConstraints(r)
+		if err != nil {
+			return "", err
+		}
+		all = append(all, c)
+	}
+
+	versions, err := u.availableVersions(ctx, source)
+	if err != nil {
+		return "", err
+	}
+
+	for i := len(versions) - 1; i >= 0; i-- {
+		ok := true
+		for _, c := range all {
+			if !c.Check(versions[i]) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return versions[i].String(), nil
+		}
+	}
+	return "", fmt.Errorf("no published version of %s matches %s", source, strings.Join(raw, ", "))
+}
+
+// packageHashes computes the lock file hashes of a release: an "h1:" hash of
+// the package contents for each configured platform, computed from an
+// archive verified against the registry checksum. The release's SHA256SUMS
+// is not read, since its signature is not checked. Packages of a mirror
+// come with their hashes.
+func (u *Updater) packageHashes(ctx context.Context, source, version string) ([]string, error) {
+	platforms := u.LockPlatforms
+	if len(platforms) == 0 {
+		platforms = []providers.Platform{providers.CurrentPlatform()}
+	}
+
+	seen := make(map[string]bool)
+	var hashes []string
+	add := func(h string) {
+		if !seen[h] {
+			seen[h] = true
+			hashes = append(hashes, h)
+		}
+	}
+
+	for _, platform := range platforms {
+		pkg, err := u.Client.GetPackage(ctx, source, version, platform)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", platform, err)
+		}
+
+		if len(pkg.Hashes) > 0 {
+			for _, h := range pkg.Hashes {
+				add(h)
+			}
+			continue
+		}
+
+		if pkg.SHASum == "" {
+			return nil, fmt.Errorf("%s: no checksum to verify %s against", platform, pkg.Filename)
+		}
+		zipPath, err := u.Client.DownloadPackage(ctx, pkg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", platform, err)
+		}
+		h1, err := dirhash.HashZip(zipPath, dirhash.Hash1)
+		os.Remove(zipPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", platform, err)
+		}
+		add(h1)
+	}
+
+	sort.Strings(hashes)
+	return hashes, nil
+}
@@ -0,0 +1,237 @@
+package updater
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/sumdb/dirhash"
+
+	"warike/base/internal/flavor"
+	"warike/base/internal/lockfile"
+	"warike/base/internal/providers"
+)
+
+func TestUpdateProject_SyncsLockFile(t *testing.T) {
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	w, _ := zw.Create("terraform-provider-aws_v5.31.0")
+	w.Write([]byte("binary"))
+	zw.Close()
+
+	sum := sha256.Sum256(archive.Bytes())
+	shasum := hex.EncodeToString(sum[:])
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/hashicorp/aws/versions", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"versions": [{"version": "5.30.0"}, {"version": "5.31.0"}]}`))
+	})
+	mux.HandleFunc("/hashicorp/aws/5.31.0/download/linux/amd64", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"filename": "aws_linux_amd64.zip", "download_url": "%[1]s/aws.zip",
+			"shasums_url": "%[1]s/SHA256SUMS", "shasum": %[2]q}`, server.URL, shasum)
+	})
+	mux.HandleFunc("/aws.zip", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive.Bytes())
+	})
+	mux.HandleFunc("/SHA256SUMS", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s  aws_linux_amd64.zip\nabcd  aws_darwin_arm64.zip\n", shasum)
+	})
+
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"versions.tf": `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.30.0"
+    }
+  }
+}
+`,
+		lockfile.FileName: `provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.30.0"
+  constraints = "~> 5.30.0"
+  hashes = [
+    "h1:old",
+  ]
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	u := NewUpdater()
+	u.Client = &providers.Client{BaseURL: server.URL, HTTPClient: server.Client()}
+	u.LockPlatforms = []providers.Platform{{OS: "linux", Arch: "amd64"}}
+
+	result, err := u.UpdateProject(t.Context(), tmpDir)
+	if err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	if len(result.Files) != 2 {
+		t.Fatalf("Expected versions.tf and the lock file to change, got %d files", len(result.Files))
+	}
+
+	zipPath := filepath.Join(t.TempDir(), "aws.zip")
+	if err := os.WriteFile(zipPath, archive.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	h1, err := dirhash.HashZip(zipPath, dirhash.Hash1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(tmpDir, lockfile.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`version     = "5.31.0"`,
+		`constraints = "~> 5.31.0"`,
+		`"` + h1 + `"`,
+	} {
+		if !strings.Contains(string(got), s) {
+			t.Errorf("Expected lock file to contain %s:\n%s", s, got)
+		}
+	}
+	if strings.Contains(string(got), "h1:old") {
+		t.Errorf("Old hash was kept:\n%s", got)
+	}
+	// The unsigned SHA256SUMS is not trusted.
+	if strings.Contains(string(got), "zh:") {
+		t.Errorf("Expected only h1: hashes of verified packages:\n%s", got)
+	}
+}
+
+func TestUpdateProject_DryRunSkipsPackages(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/hashicorp/aws/versions", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"versions": [{"version": "5.30.0"}, {"version": "5.31.0"}]}`))
+	})
+	mux.HandleFunc("/hashicorp/aws/5.31.0/download/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Dry run requested package %s", r.URL.Path)
+		http.NotFound(w, r)
+	})
+
+	tmpDir := t.TempDir()
+	lock := `provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.30.0"
+  constraints = "~> 5.30.0"
+  hashes = [
+    "h1:old",
+  ]
+}
+`
+	files := map[string]string{
+		"versions.tf": `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.30.0"
+    }
+  }
+}
+`,
+		lockfile.FileName: lock,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	u := NewUpdater()
+	u.Client = &providers.Client{BaseURL: server.URL, HTTPClient: server.Client()}
+	u.LockPlatforms = []providers.Platform{{OS: "linux", Arch: "amd64"}}
+	u.DryRun = true
+
+	result, err := u.UpdateProject(t.Context(), tmpDir)
+	if err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	if len(result.Failed) != 0 {
+		t.Errorf("Expected no failures, got %+v", result.Failed)
+	}
+
+	var diff string
+	for _, f := range result.Files {
+		if filepath.Base(f.Path) == lockfile.FileName {
+			diff = string(f.After)
+		}
+	}
+	if !strings.Contains(diff, `version     = "5.31.0"`) {
+		t.Errorf("Expected the lock file change to report 5.31.0, got:\n%s", diff)
+	}
+
+	got, err := os.ReadFile(filepath.Join(tmpDir, lockfile.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != lock {
+		t.Errorf("Dry run wrote the lock file:\n%s", got)
+	}
+}
+
+func TestUpdateProject_OpenTofu(t *testing.T) {
+	u, _ := newTestUpdater(t, "5.30.0", "5.31.0")
+	u.Flavor = flavor.OpenTofu
+
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"versions.tofu": `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.30"
+    }
+  }
+}
+`,
+		lockfile.FileName: `provider "registry.opentofu.org/hashicorp/aws" {
+  version     = "5.31.0"
+  constraints = "~> 5.30"
+  hashes = [
+    "h1:kept",
+  ]
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := u.UpdateProject(t.Context(), tmpDir)
+	if err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	if len(result.Changes) != 1 || filepath.Base(result.Changes[0].File) != "versions.tofu" {
+		t.Fatalf("Expected versions.tofu to be updated, got %+v", result.Changes)
+	}
+
+	got, err := os.ReadFile(filepath.Join(tmpDir, lockfile.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), `constraints = "~> 5.31"`) || !strings.Contains(string(got), "h1:kept") {
+		t.Errorf("Expected the registry.opentofu.org entry to be updated:\n%s", got)
+	}
+}
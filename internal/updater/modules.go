@@ -0,0 +1,302 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"warike/base/internal/gitsource"
+	"warike/base/internal/providers"
+	"warike/base/internal/semver"
+)
+
+// ModuleCall describes a module block whose version can be updated: a
+// registry module with a version argument, or a git module whose ?ref= is a
+// version tag.
+type ModuleCall struct {
+	Name    string
+	Source  string
+	Version string
+	// File is the path of the configuration file declaring the module.
+	File string
+
+	// git is set for git sources; Version is then its ref.
+	git *gitSource
+	// address is the registry address of registry modules: Source without
+	// its //subdir.
+	address string
+
+	// versionRange locates the version expression of registry modules and
+	// the source expression of git modules.
+	versionRange hcl.Range
+}
+
+// gitSource is a git module source split around the value of its ref
+// argument, so that only the ref is rewritten.
+type gitSource struct {
+	// url is what git clones: the source without the git:: forcing prefix,
+	// the //subdir and the query.
+	url            string
+	prefix, suffix string
+}
+
+// parseModuleCalls returns the updatable module blocks of src in source
+// order. Local paths, other remote sources and modules without a version
+// or version ref are left out.
+func parseModuleCalls(filename string, src []byte) ([]ModuleCall, error) {
+	file, diags := hclsyntax.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("%s: unexpected body type %T", filename, file.Body)
+	}
+
+	var found []ModuleCall
+	for _, block := range body.Blocks {
+		if block.Type != "module" || len(block.Labels) != 1 {
+			continue
+		}
+		m := ModuleCall{Name: block.Labels[0]}
+
+		attr, ok := block.Body.Attributes["source"]
+		if !ok {
+			continue
+		}
+		// Sources must be literal strings; anything else is Terraform's to
+		// report.
+		if m.Source, _ = stringValue(attr.Expr); m.Source == "" {
+			continue
+		}
+
+		if git, ref, ok := newGitSource(m.Source); ok {
+			// Branches and commit refs are not versions.
+			if _, err := semver.Parse(ref); err != nil {
+				continue
+			}
+			m.git, m.Version = git, ref
+			m.versionRange = attr.Expr.Range()
+		} else if address, _, _ := strings.Cut(m.Source, "//"); providers.IsRegistryModule(address) {
+			m.address = address
+			version, ok := block.Body.Attributes["version"]
+			if !ok {
+				continue
+			}
+			v, err := stringValue(version.Expr)
+			if err != nil {
+				return nil, fmt.Errorf("%s: module %q version: %w", version.Expr.Range(), m.Name, err)
+			}
+			m.Version = v
+			m.versionRange = version.Expr.Range()
+		} else {
+			continue
+		}
+
+		found = append(found, m)
+	}
+	return found, nil
+}
+
+// newGitSource splits a git module source: git::<url>, github.com/...,
+// bitbucket.org/... or git@host:path, with an optional //subdir and a ref
+// query argument. ok is false for other sources and sources without ref.
+func newGitSource(source string) (git *gitSource, ref string, ok bool) {
+	rest := strings.TrimPrefix(source, "git::")
+	switch {
+	case rest != source, strings.HasPrefix(rest, "git@"):
+	case strings.HasPrefix(rest, "github.com/"), strings.HasPrefix(rest, "bitbucket.org/"):
+		rest = "https://" + rest
+	default:
+		return nil, "", false
+	}
+
+	base, ref, _ := gitsource.Split(rest)
+	if ref == "" {
+		return nil, "", false
+	}
+
+	// Locate the raw ref value, the last one like gitsource.Split reads, to
+	// keep everything around it.
+	start := max(strings.LastIndex(source, "?ref="), strings.LastIndex(source, "&ref=")) + len("?ref=")
+	end := start + len(ref)
+	return &gitSource{url: base, prefix: source[:start], suffix: source[end:]}, ref, true
+}
+
+// withRef returns the source with ref in place of its current ref.
+func (g *gitSource) withRef(ref string) string {
+	return g.prefix + url.QueryEscape(ref) + g.suffix
+}
+
+// gitTags lists the tags of the repository at url from a bare, blob-less
+// clone into a temporary directory.
+func gitTags(ctx context.Context, url string) ([]string, error) {
+	if err := gitsource.CheckURL(url); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.MkdirTemp("", "tfinit-module-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	clone := exec.CommandContext(ctx, "git", "clone", "--quiet", "--bare", "--filter=blob:none", "--", url, tmp)
+	if out, err := clone.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("cloning %s: %w: %s", url, err, strings.TrimSpace(string(out)))
+	}
+
+	out, err := exec.CommandContext(ctx, "git", "-C", tmp, "tag", "--list").Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing tags of %s: %w", url, err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// tagVersions maps the version tags among tags to their version, keeping
+// only tags written like ref: with a "v" prefix when ref has one.
+func tagVersions(tags []string, ref string) ([]semver.Version, map[string]string) {
+	prefixed := strings.HasPrefix(ref, "v")
+	byVersion := make(map[string]string)
+	var versions []semver.Version
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, "v") != prefixed {
+			continue
+		}
+		v, err := semver.Parse(tag)
+		if err != nil {
+			continue
+		}
+		if _, dup := byVersion[v.String()]; !dup {
+			versions = append(versions, v)
+		}
+		byVersion[v.String()] = tag
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].LessThan(versions[j])
+	})
+	return versions, byVersion
+}
+
+// lookupKey names the query of m's versions: the registry module, or the
+// repository that git modules are cloned from.
+func (m ModuleCall) lookupKey() string {
+	if m.git != nil {
+		return "git " + m.git.url
+	}
+	return "module " + m.address
+}
+
+// policySource is the source that policy overrides name m by: the registry
+// address, or the source as written for git modules.
+func (m ModuleCall) policySource() string {
+	if m.address != "" {
+		return m.address
+	}
+	return m.Source
+}
+
+// moduleVersions returns the releases of the module called by m sorted
+// ascending and, for git sources, the tag of each version.
+func (u *Updater) moduleVersions(ctx context.Context, m ModuleCall) ([]semver.Version, map[string]string, error) {
+	l := u.lookup(m.lookupKey(), func() lookup {
+		if m.git != nil {
+			tags, err := gitTags(ctx, m.git.url)
+			return lookup{tags: tags, err: err}
+		}
+		versions, err := u.Client.ListModuleVersions(ctx, m.address)
+		return lookup{versions: versions, err: err}
+	})
+	if l.err != nil || m.git == nil {
+		return l.versions, nil, l.err
+	}
+	versions, tags := tagVersions(l.tags, m.Version)
+	return versions, tags, nil
+}
+
+// updateModuleCalls bumps the module calls of f under the same policies as
+// providers, recording changes, skips and failures in result, and returns
+// the edits to apply to f.
+func (u *Updater) updateModuleCalls(ctx context.Context, f *moduleFile, result *ModuleResult) ([]versionEdit, error) {
+	var edits []versionEdit
+	for _, m := range f.Modules {
+		line := m.versionRange.Start.Line
+
+		// Git refs are exact versions, written with or without a "v".
+		parsed, err := semver.ParseConstraints(strings.TrimPrefix(m.Version, "v"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: module %s: %w", f.Path, m.Name, err)
+		}
+
+		versions, tags, err := u.moduleVersions(ctx, m)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			result.Failed = append(result.Failed, Failure{
+				Kind:   KindModule,
+				Module: result.Path,
+				File:   f.Path,
+				Line:   line,
+				Name:   m.Name,
+				Source: m.Source,
+				Err:    err,
+			})
+			continue
+		}
+
+		policy := u.policyFor(m.policySource(), m.Name)
+		picked, newest, ok := selectVersion(versions, parsed, policy)
+		if !ok {
+			continue
+		}
+
+		if picked.LessThan(newest) {
+			result.Skipped = append(result.Skipped, Skip{
+				Kind:       KindModule,
+				Module:     result.Path,
+				File:       f.Path,
+				Line:       line,
+				Name:       m.Name,
+				Source:     m.Source,
+				Constraint: m.Version,
+				Latest:     newest.String(),
+				Policy:     policy,
+				Major:      newest.Major > picked.Major,
+			})
+		}
+
+		next, bumped := parsed.Bump(picked)
+		rewritten := next
+		if m.git != nil {
+			next = tags[picked.String()]
+			bumped = next != "" && next != m.Version
+			rewritten = m.git.withRef(next)
+		}
+		if !bumped {
+			continue
+		}
+
+		result.Changes = append(result.Changes, Change{
+			Kind:   KindModule,
+			Module: result.Path,
+			File:   f.Path,
+			Line:   line,
+			Name:   m.Name,
+			Source: m.Source,
+			From:   m.Version,
+			To:     next,
+			Latest: newest.String(),
+		})
+		edits = append(edits, versionEdit{Range: m.versionRange, Version: rewritten})
+	}
+	return edits, nil
+}
@@ -0,0 +1,290 @@
+package updater
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"warike/base/internal/providers"
+)
+
+func TestNewGitSource(t *testing.T) {
+	tests := []struct {
+		source, url, ref, rewritten string
+		ok                          bool
+	}{
+		{
+			source:    "git::https://example.com/network.git//modules/vpc?ref=v1.2.0",
+			url:       "https://example.com/network.git",
+			ref:       "v1.2.0",
+			rewritten: "git::https://example.com/network.git//modules/vpc?ref=v2.0.0",
+			ok:        true,
+		},
+		{
+			source:    "github.com/acme/terraform-vpc?depth=1&ref=1.2.0",
+			url:       "https://github.com/acme/terraform-vpc",
+			ref:       "1.2.0",
+			rewritten: "github.com/acme/terraform-vpc?depth=1&ref=v2.0.0",
+			ok:        true,
+		},
+		{
+			source:    "git@github.com:acme/vpc.git?ref=v1.2.0&depth=1",
+			url:       "git@github.com:acme/vpc.git",
+			ref:       "v1.2.0",
+			rewritten: "git@github.com:acme/vpc.git?ref=v2.0.0&depth=1",
+			ok:        true,
+		},
+		{source: "git::https://example.com/network.git", ok: false},
+		{source: "terraform-aws-modules/vpc/aws", ok: false},
+		{source: "./modules/vpc", ok: false},
+	}
+	for _, tt := range tests {
+		git, ref, ok := newGitSource(tt.source)
+		if ok != tt.ok {
+			t.Errorf("newGitSource(%q) ok = %v, want %v", tt.source, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if git.url != tt.url || ref != tt.ref {
+			t.Errorf("newGitSource(%q) = %q, %q, want %q, %q", tt.source, git.url, ref, tt.url, tt.ref)
+		}
+		if got := git.withRef("v2.0.0"); got != tt.rewritten {
+			t.Errorf("withRef() = %q, want %q", got, tt.rewritten)
+		}
+	}
+}
+
+func TestParseModuleCalls(t *testing.T) {
+	content := `module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "~> 5.1"
+}
+
+module "local" {
+  source = "./modules/local"
+}
+
+module "unversioned" {
+  source = "terraform-aws-modules/eks/aws"
+}
+
+module "branch" {
+  source = "git::https://example.com/network.git?ref=main"
+}
+
+module "tagged" {
+  source = "git::https://example.com/network.git?ref=v1.0.0"
+}
+`
+	calls, err := parseModuleCalls("main.tf", []byte(content))
+	if err != nil {
+		t.Fatalf("parseModuleCalls() error = %v", err)
+	}
+	if len(calls) != 2 || calls[0].Name != "vpc" || calls[1].Name != "tagged" {
+		t.Fatalf("parseModuleCalls() = %+v, want vpc and tagged", calls)
+	}
+	if calls[0].Version != "~> 5.1" || calls[1].Version != "v1.0.0" {
+		t.Errorf("versions = %q, %q", calls[0].Version, calls[1].Version)
+	}
+}
+
+func TestUpdateProject_RegistryModules(t *testing.T) {
+	content := `module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.1.0"
+}
+`
+	want := `module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.8.0"
+}
+`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/modules/terraform-aws-modules/vpc/aws/versions", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"modules": [{"versions": [{"version": "5.1.0"}, {"version": "5.8.0"}, {"version": "6.0.0"}]}]}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	u := NewUpdater()
+	u.Client = &providers.Client{BaseURL: server.URL + "/v1/providers", HTTPClient: server.Client()}
+	u.Modules = true
+	u.Policy = AllowMinor
+
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "main.tf")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := u.UpdateProject(t.Context(), tmpDir)
+	if err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	if len(result.Changes) != 1 || result.Changes[0].Kind != KindModule || result.Changes[0].To != "5.8.0" {
+		t.Errorf("Changes = %v, want vpc updated to 5.8.0", result.Changes)
+	}
+	if len(result.Skipped) != 1 || !result.Skipped[0].Major {
+		t.Errorf("Skipped = %v, want the 6.0.0 major upgrade held back", result.Skipped)
+	}
+
+	got, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("Unexpected rewrite:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUpdateProject_RegistryModuleSubdir(t *testing.T) {
+	content := `module "endpoints" {
+  source  = "terraform-aws-modules/vpc/aws//modules/vpc-endpoints"
+  version = "5.1.0"
+}
+`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/modules/terraform-aws-modules/vpc/aws/versions", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"modules": [{"versions": [{"version": "5.1.0"}, {"version": "5.8.0"}]}]}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	u := NewUpdater()
+	u.Client = &providers.Client{BaseURL: server.URL + "/v1/providers", HTTPClient: server.Client()}
+	u.Modules = true
+
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "main.tf")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := u.UpdateProject(t.Context(), tmpDir)
+	if err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	if len(result.Changes) != 1 || result.Changes[0].To != "5.8.0" {
+		t.Errorf("Changes = %v, want endpoints updated to 5.8.0", result.Changes)
+	}
+
+	got, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(content, "5.1.0", "5.8.0", 1); string(got) != want {
+		t.Errorf("Unexpected rewrite:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUpdateProject_GitModules(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, "main.tf"), []byte("# network\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch", "main"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "network"},
+		{"tag", "v1.0.0"},
+		{"tag", "v1.1.0"},
+		{"tag", "2.0.0"},
+		{"tag", "release"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	source := "git::file://" + filepath.ToSlash(repo) + "?ref="
+	content := "module \"network\" {\n  source = \"" + source + "v1.0.0\"\n}\n"
+	want := "module \"network\" {\n  source = \"" + source + "v1.1.0\"\n}\n"
+
+	u := NewUpdater()
+	u.Modules = true
+
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "main.tf")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := u.UpdateProject(t.Context(), tmpDir)
+	if err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	// 2.0.0 is written without the "v" of the current ref and ignored.
+	if len(result.Changes) != 1 || result.Changes[0].From != "v1.0.0" || result.Changes[0].To != "v1.1.0" {
+		t.Fatalf("Changes = %v, want network updated from v1.0.0 to v1.1.0", result.Changes)
+	}
+
+	got, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("Unexpected rewrite:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUpdateProject_ModulesFailure(t *testing.T) {
+	content := `module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.1.0"
+}
+`
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	u := NewUpdater()
+	u.Client = &providers.Client{BaseURL: server.URL, HTTPClient: server.Client()}
+	u.Modules = true
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.tf"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := u.UpdateProject(t.Context(), tmpDir)
+	if err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	if len(result.Failed) != 1 || result.Failed[0].Kind != KindModule || result.Failed[0].Name != "vpc" {
+		t.Errorf("Failed = %v, want the vpc module", result.Failed)
+	}
+}
+
+func TestUpdateProject_GitOptionSource(t *testing.T) {
+	tmpDir := t.TempDir()
+	marker := filepath.Join(tmpDir, "pwned")
+	content := "module \"evil\" {\n  source = \"git::--upload-pack=touch " + filepath.ToSlash(marker) + "?ref=v1.0.0\"\n}\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.tf"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	u := NewUpdater()
+	u.Modules = true
+
+	result, err := u.UpdateProject(t.Context(), tmpDir)
+	if err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	if len(result.Failed) != 1 || !strings.Contains(result.Failed[0].Err.Error(), "invalid git URL") {
+		t.Errorf("Failed = %v, want the evil module rejected", result.Failed)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("git ran the --upload-pack of the source")
+	}
+}
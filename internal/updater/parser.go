@@ -0,0 +1,172 @@
+package updater
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ProviderInfo describes one entry of a required_providers block.
+type ProviderInfo struct {
+	// Name is the local name the module uses for the provider, e.g. "aws".
+	Name    string
+	Source  string
+	Version string
+	// File is the path of the configuration file declaring the requirement.
+	File string
+
+	// versionRange locates the version expression in the source file so it
+	// can be replaced without touching the surrounding text.
+	versionRange hcl.Range
+}
+
+// versionEdit replaces the expression at Range with a quoted version string.
+type versionEdit struct {
+	Range   hcl.Range
+	Version string
+}
+
+// parseRequiredProviders walks every terraform { required_providers { ... } }
+// block in src and returns the provider requirements in source order.
+func parseRequiredProviders(filename string, src []byte) ([]ProviderInfo, error) {
+	file, diags := hclsyntax.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("%s: unexpected body type %T", filename, file.Body)
+	}
+
+	var found []ProviderInfo
+	for _, block := range body.Blocks {
+		if block.Type != "terraform" {
+			continue
+		}
+		for _, inner := range block.Body.Blocks {
+			if inner.Type != "required_providers" {
+				continue
+			}
+			for _, attr := range sortedAttributes(inner.Body.Attributes) {
+				p, err := parseRequirement(attr)
+				if err != nil {
+					return nil, err
+				}
+				found = append(found, p)
+			}
+		}
+	}
+
+	return found, nil
+}
+
+// parseRequirement decodes a single required_providers entry. Both the object
+// form (aws = { source = "...", version = "..." }) and the legacy string form
+// (aws = "~> 5.0") are accepted.
+func parseRequirement(attr *hclsyntax.Attribute) (ProviderInfo, error) {
+	p := ProviderInfo{Name: attr.Name}
+
+	obj, ok := attr.Expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		version, err := stringValue(attr.Expr)
+		if err != nil {
+			return p, fmt.Errorf("%s: provider %q: %w", attr.SrcRange, attr.Name, err)
+		}
+		p.Version = version
+		p.versionRange = attr.Expr.Range()
+	}
+
+	if obj != nil {
+		for _, item := range obj.Items {
+			key, err := objectKey(item.KeyExpr)
+			if err != nil {
+				return p, fmt.Errorf("%s: provider %q: %w", item.KeyExpr.Range(), attr.Name, err)
+			}
+
+			switch key {
+			case "source":
+				source, err := stringValue(item.ValueExpr)
+				if err != nil {
+					return p, fmt.Errorf("%s: provider %q source: %w", item.ValueExpr.Range(), attr.Name, err)
+				}
+				p.Source = source
+			case "version":
+				version, err := stringValue(item.ValueExpr)
+				if err != nil {
+					return p, fmt.Errorf("%s: provider %q version: %w", item.ValueExpr.Range(), attr.Name, err)
+				}
+				p.Version = version
+				p.versionRange = item.ValueExpr.Range()
+			}
+		}
+	}
+
+	// Terraform implies the hashicorp namespace when no source is given.
+	if p.Source == "" {
+		p.Source = "hashicorp/" + attr.Name
+	}
+
+	return p, nil
+}
+
+func objectKey(expr hclsyntax.Expression) (string, error) {
+	if keyword := hcl.ExprAsKeyword(expr); keyword != "" {
+		return keyword, nil
+	}
+	return stringValue(expr)
+}
+
+func stringValue(expr hclsyntax.Expression) (string, error) {
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return "", diags
+	}
+	if val.IsNull() || !val.IsKnown() || val.Type() != cty.String {
+		return "", fmt.Errorf("expected a string, got %s", val.Type().FriendlyName())
+	}
+	// Heredocs carry a trailing newline that is not part of the value.
+	return strings.TrimSpace(val.AsString()), nil
+}
+
+func sortedAttributes(attrs hclsyntax.Attributes) []*hclsyntax.Attribute {
+	sorted := make([]*hclsyntax.Attribute, 0, len(attrs))
+	for _, attr := range attrs {
+		sorted = append(sorted, attr)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].SrcRange.Start.Byte < sorted[j].SrcRange.Start.Byte
+	})
+	return sorted
+}
+
+// rewriteVersions returns a copy of src with each edited expression replaced
+// by a quoted version string. Everything else, comments and alignment
+// included, is preserved byte for byte.
+func rewriteVersions(src []byte, edits []versionEdit) []byte {
+	sorted := make([]versionEdit, len(edits))
+	copy(sorted, edits)
+	// Apply from the end of the file so earlier offsets stay valid.
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Range.Start.Byte > sorted[j].Range.Start.Byte
+	})
+
+	out := make([]byte, len(src))
+	copy(out, src)
+	for _, e := range sorted {
+		quoted := hclwrite.TokensForValue(cty.StringVal(e.Version)).Bytes()
+
+		var buf []byte
+		buf = append(buf, out[:e.Range.Start.Byte]...)
+		buf = append(buf, quoted...)
+		buf = append(buf, out[e.Range.End.Byte:]...)
+		out = buf
+	}
+
+	return out
+}
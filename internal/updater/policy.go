@@ -0,0 +1,127 @@
+package updater
+
+import (
+	"fmt"
+
+	"warike/base/internal/semver"
+)
+
+// Policy limits how far the updater may move a provider constraint.
+type Policy string
+
+const (
+	AllowPatch Policy = "patch"
+	AllowMinor Policy = "minor"
+	AllowMajor Policy = "major"
+)
+
+// ParsePolicy validates a policy name. An empty string selects AllowMajor.
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case AllowPatch, AllowMinor, AllowMajor:
+		return p, nil
+	case "":
+		return AllowMajor, nil
+	}
+	return "", fmt.Errorf("invalid update policy %q: must be patch, minor or major", s)
+}
+
+// allows reports whether moving from current to candidate stays within the
+// policy.
+func (p Policy) allows(current, candidate semver.Version) bool {
+	switch p {
+	case AllowPatch:
+		return candidate.Major == current.Major && candidate.Minor == current.Minor
+	case AllowMinor:
+		return candidate.Major == current.Major
+	}
+	return true
+}
+
+// selectVersion picks the newest stable release allowed by policy for a
+// requirement. The policy is measured from the version the constraints
+// declare, so "~> 5" stays on 5.0.x under the patch policy even though it
+// admits 6.x. Releases excluded by a "!=" term are never picked. It also
+// returns the newest stable release overall so callers can report upgrades
+// the policy held back. versions must be sorted ascending.
+func selectVersion(versions []semver.Version, constraints semver.Constraints, policy Policy) (picked, newest semver.Version, ok bool) {
+	current, found := currentVersion(versions, constraints)
+	if !found {
+		return semver.Version{}, semver.Version{}, false
+	}
+
+	declared := declaredVersion(constraints, current)
+	picked = current
+	if !policy.allows(declared, current) {
+		picked = declared
+	}
+	for _, v := range versions {
+		if v.IsPrerelease() || excluded(constraints, v) {
+			continue
+		}
+		if !v.LessThan(picked) && policy.allows(declared, v) {
+			picked = v
+		}
+		if !v.LessThan(current) {
+			newest = v
+		}
+	}
+	if newest.LessThan(current) {
+		newest = current
+	}
+
+	return picked, newest, true
+}
+
+// declaredVersion is the highest lower bound the constraints name, such as
+// 5.30.0 for "~> 5.30" or 4.0.0 for ">= 4.0, < 5.0". Constraints without a
+// lower bound declare current.
+func declaredVersion(constraints semver.Constraints, current semver.Version) semver.Version {
+	declared, found := semver.Version{}, false
+	for _, t := range constraints.Terms() {
+		switch t.Op {
+		case "=", ">", ">=", "~>":
+			if !found || declared.LessThan(t.Version) {
+				declared, found = t.Version, true
+			}
+		}
+	}
+	if !found {
+		return current
+	}
+	return declared
+}
+
+// excluded reports whether a "!=" term of the constraints rules out v.
+func excluded(constraints semver.Constraints, v semver.Version) bool {
+	for _, t := range constraints.Terms() {
+		if t.Op == "!=" && !t.Check(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// currentVersion is the release Terraform would select today: the newest
+// published version satisfying the constraints. When none does, the highest
+// version named by the constraints is used as the baseline instead.
+func currentVersion(versions []semver.Version, constraints semver.Constraints) (semver.Version, bool) {
+	for i := len(versions) - 1; i >= 0; i-- {
+		if constraints.Check(versions[i]) {
+			return versions[i], true
+		}
+	}
+
+	var baseline semver.Version
+	found := false
+	for _, t := range constraints.Terms() {
+		if t.Op == "!=" {
+			continue
+		}
+		if !found || baseline.LessThan(t.Version) {
+			baseline = t.Version
+			found = true
+		}
+	}
+	return baseline, found
+}
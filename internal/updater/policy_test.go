@@ -0,0 +1,147 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"warike/base/internal/semver"
+)
+
+func TestSelectVersion(t *testing.T) {
+	published := []string{"4.0.0", "4.1.0", "4.1.3", "4.2.0", "5.0.0", "5.1.0", "6.0.0-beta1"}
+	var versions []semver.Version
+	for _, v := range published {
+		versions = append(versions, semver.MustParse(v))
+	}
+
+	tests := []struct {
+		constraint string
+		policy     Policy
+		wantPicked string
+		wantNewest string
+	}{
+		{"4.1.0", AllowPatch, "4.1.3", "5.1.0"},
+		{"4.1.0", AllowMinor, "4.2.0", "5.1.0"},
+		{"4.1.0", AllowMajor, "5.1.0", "5.1.0"},
+		// The patch policy keeps the declared 4.0 floor.
+		{"~> 4.0", AllowPatch, "4.0.0", "5.1.0"},
+		{"~> 4.0", AllowMinor, "4.2.0", "5.1.0"},
+		{">= 4.0, < 5.0", AllowMinor, "4.2.0", "5.1.0"},
+		{"3.9.0", AllowMinor, "3.9.0", "5.1.0"},
+	}
+
+	for _, tt := range tests {
+		c, err := semver.ParseConstraints(tt.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		picked, newest, ok := selectVersion(versions, c, tt.policy)
+		if !ok {
+			t.Fatalf("selectVersion(%q, %s) found no version", tt.constraint, tt.policy)
+		}
+		if picked.String() != tt.wantPicked || newest.String() != tt.wantNewest {
+			t.Errorf("selectVersion(%q, %s) = %s, %s; want %s, %s",
+				tt.constraint, tt.policy, picked, newest, tt.wantPicked, tt.wantNewest)
+		}
+	}
+}
+
+func TestSelectVersion_Bump(t *testing.T) {
+	published := []string{"4.0.0", "5.0.0", "5.0.2", "5.30.0", "5.30.1", "5.31.0", "6.1.0", "6.2.0"}
+	var versions []semver.Version
+	for _, v := range published {
+		versions = append(versions, semver.MustParse(v))
+	}
+
+	tests := []struct {
+		constraint string
+		policy     Policy
+		want       string
+	}{
+		// Excluded releases are never picked.
+		{"= 4.0.0, != 6.2.0", AllowMajor, "= 6.1.0, != 6.2.0"},
+		{"~> 5.30, != 5.31.0", AllowMinor, "~> 5.30, != 5.31.0"},
+		{"~> 5.30, != 6.2.0", AllowMajor, "~> 6.1, != 6.2.0"},
+		// "~> 5" admits 6.x, but the patch and minor policies count from 5.
+		{"~> 5", AllowPatch, "~> 5"},
+		{"~> 5", AllowMinor, "~> 5"},
+		{"~> 5", AllowMajor, "~> 6"},
+		{"5.0.0", AllowPatch, "5.0.2"},
+	}
+
+	for _, tt := range tests {
+		c, err := semver.ParseConstraints(tt.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		picked, _, ok := selectVersion(versions, c, tt.policy)
+		if !ok {
+			t.Fatalf("selectVersion(%q, %s) found no version", tt.constraint, tt.policy)
+		}
+		got := tt.constraint
+		if next, bumped := c.Bump(picked); bumped {
+			got = next
+		}
+		if got != tt.want {
+			t.Errorf("selectVersion(%q, %s) = %s, bumped to %q; want %q", tt.constraint, tt.policy, picked, got, tt.want)
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	if p, err := ParsePolicy(""); err != nil || p != AllowMajor {
+		t.Errorf("ParsePolicy(\"\") = %q, %v; want major", p, err)
+	}
+	if _, err := ParsePolicy("latest"); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+}
+
+func TestUpdateProject_Policy(t *testing.T) {
+	content := `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.30"
+    }
+    google = {
+      source  = "hashicorp/google"
+      version = "5.30.0"
+    }
+  }
+}
+`
+	u, _ := newTestUpdater(t, "5.30.0", "5.30.2", "5.31.0", "6.0.0")
+	u.Policy = AllowMinor
+	u.Overrides = map[string]Policy{"google": AllowPatch}
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "versions.tf"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := u.UpdateProject(t.Context(), tmpDir)
+	if err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+
+	if len(result.Changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %v", result.Changes)
+	}
+	if result.Changes[0].To != "~> 5.31" {
+		t.Errorf("aws: got %q, want %q", result.Changes[0].To, "~> 5.31")
+	}
+	if result.Changes[1].To != "5.30.2" {
+		t.Errorf("google: got %q, want %q", result.Changes[1].To, "5.30.2")
+	}
+
+	if len(result.Skipped) != 2 {
+		t.Fatalf("Expected 2 skipped upgrades, got %v", result.Skipped)
+	}
+	for _, s := range result.Skipped {
+		if s.Latest != "6.0.0" || !s.Major {
+			t.Errorf("Unexpected skip: %+v", s)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FindModules walks root and returns every directory containing .tf or .tofu
+// files. The .terraform working directories, hidden directories and any
+// directory whose path relative to root matches one of the ignore globs are
+// skipped.
+func FindModules(root string, ignore []string) ([]string, error) {
+	var modules []string
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+
+		if path != root {
+			name := d.Name()
+			if name == ".terraform" || strings.HasPrefix(name, ".") {
+				return filepath.SkipDir
+			}
+
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			if ignored(filepath.ToSlash(rel), ignore) {
+				return filepath.SkipDir
+			}
+		}
+
+		tfFiles, err := configFiles(path)
+		if err != nil {
+			return err
+		}
+		if len(tfFiles) > 0 {
+			modules = append(modules, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(modules)
+	return modules, nil
+}
+
+// ignored reports whether rel matches one of the globs, either as a whole
+// path or by its final element.
+func ignored(rel string, globs []string) bool {
+	base := filepath.Base(rel)
+	for _, g := range globs {
+		g = strings.TrimSuffix(filepath.ToSlash(g), "/")
+		if ok, _ := filepath.Match(g, rel); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(g, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateRecursive runs UpdateProject on every module below root. Modules that
+// declare no providers are left out of the results. A module that fails
+// does not stop the others: the results of every module, including what
+// the failing ones changed before failing, are returned along with the
+// joined errors.
+func (u *Updater) UpdateRecursive(ctx context.Context, root string, ignore []string) ([]ModuleResult, error) {
+	modules, err := FindModules(root, ignore)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ModuleResult
+	var errs []error
+	for _, dir := range modules {
+		if err := ctx.Err(); err != nil {
+			return results, errors.Join(append(errs, err)...)
+		}
+		result, err := u.UpdateProject(ctx, dir)
+		if errors.Is(err, ErrNoRequiredProviders) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			if len(result.Files) == 0 && len(result.Changes) == 0 {
+				continue
+			}
+		}
+		results = append(results, result)
+	}
+
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,106 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const awsRequirement = `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "4.0.0"
+    }
+  }
+}
+`
+
+func writeModule(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "versions.tf"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindModules(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, filepath.Join(root, "live", "prod"), awsRequirement)
+	writeModule(t, filepath.Join(root, "modules", "vpc"), awsRequirement)
+	writeModule(t, filepath.Join(root, "live", "prod", ".terraform", "modules", "vpc"), awsRequirement)
+	writeModule(t, filepath.Join(root, "examples", "basic"), awsRequirement)
+
+	got, err := FindModules(root, []string{"examples"})
+	if err != nil {
+		t.Fatalf("FindModules failed: %v", err)
+	}
+
+	want := []string{
+		filepath.Join(root, "live", "prod"),
+		filepath.Join(root, "modules", "vpc"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("FindModules() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("FindModules()[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestUpdateRecursive_DedupesLookups(t *testing.T) {
+	u, requests := newTestUpdater(t, "4.0.0", "5.0.0")
+
+	root := t.TempDir()
+	writeModule(t, filepath.Join(root, "live", "dev"), awsRequirement)
+	writeModule(t, filepath.Join(root, "live", "prod"), awsRequirement)
+	writeModule(t, filepath.Join(root, "modules", "vpc"), `# no providers here`)
+
+	results, err := u.UpdateRecursive(t.Context(), root, nil)
+	if err != nil {
+		t.Fatalf("UpdateRecursive failed: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 module results, got %d", len(results))
+	}
+	for _, r := range results {
+		if len(r.Changes) != 1 || r.Changes[0].To != "5.0.0" {
+			t.Errorf("Unexpected changes for %s: %v", r.Path, r.Changes)
+		}
+	}
+	if *requests != 1 {
+		t.Errorf("Expected a single registry lookup across modules, got %d", *requests)
+	}
+}
+
+func TestUpdateRecursive_KeepsGoing(t *testing.T) {
+	u, _ := newTestUpdater(t, "4.0.0", "5.0.0")
+
+	root := t.TempDir()
+	writeModule(t, filepath.Join(root, "a"), awsRequirement)
+	writeModule(t, filepath.Join(root, "b"), "terraform {\n")
+	writeModule(t, filepath.Join(root, "c"), awsRequirement)
+
+	results, err := u.UpdateRecursive(t.Context(), root, nil)
+	if err == nil || !strings.Contains(err.Error(), filepath.Join(root, "b")) {
+		t.Errorf("UpdateRecursive() error = %v, want the error of module b", err)
+	}
+	if len(results) != 2 || results[0].Path != filepath.Join(root, "a") || results[1].Path != filepath.Join(root, "c") {
+		t.Fatalf("UpdateRecursive() = %+v, want the results of modules a and c", results)
+	}
+	for _, dir := range []string{"a", "c"} {
+		got, err := os.ReadFile(filepath.Join(root, dir, "versions.tf"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(got), `version = "5.0.0"`) {
+			t.Errorf("Module %s was not updated:\n%s", dir, got)
+		}
+	}
+}
@@ -1,162 +1,572 @@
 package updater
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
-	"strings"
+	"sort"
+	"sync"
 
+	"warike/base/internal/flavor"
+	"warike/base/internal/lockfile"
 	"warike/base/internal/providers"
+	"warike/base/internal/semver"
 )
 
 type Updater struct {
 	Client *providers.Client
+
+	// Policy is the default update policy. Overrides replaces it for the
+	// providers whose source or local name is used as key.
+	Policy    Policy
+	Overrides map[string]Policy
+
+	// DryRun computes the new file contents without writing them.
+	DryRun bool
+
+	// Lock keeps .terraform.lock.hcl in sync with rewritten constraints,
+	// hashing packages for LockPlatforms (the current platform when empty).
+	Lock          bool
+	LockPlatforms []providers.Platform
+
+	// Flavor selects the registry host implied by provider sources in the
+	// lock file; Client should query the same registry.
+	Flavor flavor.Flavor
+
+	// Modules also updates the versions of registry modules and the version
+	// tags of git modules called by module blocks.
+	Modules bool
+
+	// RequiredVersion raises the floor of required_version constraints to
+	// the newest Terraform or OpenTofu release the policy allows, and holds
+	// back provider releases that the oldest allowed release cannot run.
+	RequiredVersion bool
+
+	// versions caches registry lookups, failed ones included, by provider
+	// source so that modules sharing a provider only query the registry once
+	// per run.
+	mu       sync.Mutex
+	versions map[string]lookup
+}
+
+// lookup is the outcome of querying the releases of a provider or module,
+// or the tags of a git repository.
+type lookup struct {
+	versions []semver.Version
+	tags     []string
+	// protocols holds the plugin protocols of provider releases by version.
+	protocols map[string][]string
+	err       error
 }
 
 func NewUpdater() *Updater {
 	return &Updater{
 		Client: providers.NewClient(),
+		Policy: AllowMajor,
+		Lock:   true,
+	}
+}
+
+// ErrNoRequiredProviders is returned for modules without any
+// required_providers block.
+var ErrNoRequiredProviders = errors.New("no required_providers found")
+
+// Kind tells provider requirements and module calls apart in results.
+type Kind string
+
+const (
+	KindProvider Kind = "provider"
+	KindModule   Kind = "module"
+	// KindCore is the required_version constraint of the Terraform or
+	// OpenTofu CLI.
+	KindCore Kind = "core"
+)
+
+// Change records a provider constraint or module version rewritten by the
+// updater.
+type Change struct {
+	Kind   Kind
+	Module string
+	File   string
+	// Line is the line of the version argument within File.
+	Line   int
+	Name   string
+	Source string
+	From   string
+	To     string
+	// Latest is the newest stable release published for Source.
+	Latest string
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case KindModule:
+		return fmt.Sprintf("Updated module %s (%s) from %s to %s in %s", c.Name, c.Source, c.From, c.To, filepath.Base(c.File))
+	case KindCore:
+		return fmt.Sprintf("Updated %s required_version from %q to %q in %s", c.Source, c.From, c.To, filepath.Base(c.File))
 	}
+	return fmt.Sprintf("Updated %s from %s to %s in %s", c.Source, c.From, c.To, filepath.Base(c.File))
 }
 
-type ProviderInfo struct {
-	Name    string
-	Source  string
-	Version string
+// Skip records a newer release that the update policy held back.
+type Skip struct {
+	Kind       Kind
+	Module     string
+	File       string
+	Line       int
+	Name       string
+	Source     string
+	Constraint string
+	Latest     string
+	Policy     Policy
+	// Major is set when the held back release is a new major version.
+	Major bool
+	// Core is set, e.g. to "terraform 0.14.0", when the release was held
+	// back because the oldest release allowed by required_version cannot
+	// run it.
+	Core string
 }
 
+func (s Skip) String() string {
+	switch {
+	case s.Core != "":
+		return fmt.Sprintf("Skipped %s %s (constraint %q, not supported by %s)", s.Source, s.Latest, s.Constraint, s.Core)
+	case s.Kind == KindModule:
+		return fmt.Sprintf("Skipped module %s %s (version %q, allow=%s)", s.Name, s.Latest, s.Constraint, s.Policy)
+	case s.Kind == KindCore:
+		return fmt.Sprintf("Skipped %s %s (required_version %q, allow=%s)", s.Source, s.Latest, s.Constraint, s.Policy)
+	}
+	return fmt.Sprintf("Skipped %s %s (constraint %q, allow=%s)", s.Source, s.Latest, s.Constraint, s.Policy)
+}
+
+// Reason explains why the upgrade was not applied.
+func (s Skip) Reason() string {
+	if s.Core != "" {
+		return fmt.Sprintf("upgrade to %s needs a plugin protocol %s does not support", s.Latest, s.Core)
+	}
+	kind := "upgrade"
+	if s.Major {
+		kind = "major upgrade"
+	}
+	return fmt.Sprintf("%s to %s blocked by allow=%s", kind, s.Latest, s.Policy)
+}
+
+// Failure records a provider or module call that could not be checked.
+// The rest of the module is updated regardless.
+type Failure struct {
+	Kind   Kind
+	Module string
+	File   string
+	Line   int
+	Name   string
+	Source string
+	Err    error
+}
+
+func (f Failure) String() string {
+	switch f.Kind {
+	case KindModule:
+		return fmt.Sprintf("Failed to check module %s (%s): %v", f.Name, f.Source, f.Err)
+	case KindCore:
+		return fmt.Sprintf("Failed to check %s releases: %v", f.Source, f.Err)
+	}
+	return fmt.Sprintf("Failed to check %s: %v", f.Source, f.Err)
+}
+
+// FileChange holds the contents of a rewritten file before and after the
+// update.
+type FileChange struct {
+	Path   string
+	Before []byte
+	After  []byte
+}
+
+// ModuleResult is the outcome of updating a single module.
+type ModuleResult struct {
+	Path    string
+	Changes []Change
+	Skipped []Skip
+	Failed  []Failure
+	Files   []FileChange
+}
+
+// ParseProviderFile parses the terraform required_providers blocks of the
+// file at path. It returns every provider requirement found along with the
+// raw file content, which UpdateProject later rewrites in place.
 func (u *Updater) ParseProviderFile(path string) ([]ProviderInfo, string, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return nil, "", err
 	}
 
-	contentStr := string(content)
-	var foundProviders []ProviderInfo
-
-	// Regex to find providers in required_providers block
-	// This is a simplified regex and assumes standard formatting
-	// Ideally we would use hcl parser but for this task regex is sufficient per requirements
-	// Matches: aws = { source = "hashicorp/aws", version = "..." }
-	// Or more generically: name = { ... source = "..." ... version = "..." ... }
-	
-	// Let's iterate line by line for simplicity or use a multi-line regex
-	// Capture source and version
-	// Assumption: source and version are on separate lines inside the block
-	
-	// Let's try to match: source\s*=\s*"([^"]+)" and version\s*=\s*"([^"]+)"
-	// But we need to group them.
-	
-	// Simplistic approach: Find all blocks.
-	// Since we need to update the file later, simple string replacement might be best 
-	// if we can identify unique signatures.
-	
-	reSource := regexp.MustCompile(`source\s*=\s*"([^"]+)"`)
-	reVersion := regexp.MustCompile(`version\s*=\s*"([^"]+)"`)
-	
-	// This is tricky without a full parser. 
-	// Let's assume the file format generated by our tool:
-	/*
-	    aws = {
-	      source  = "hashicorp/aws"
-	      version = "5.30.0"
-	    }
-	*/
-	
-	lines := strings.Split(contentStr, "\n")
-	var currentSource string
-	
-	for _, line := range lines {
-		if match := reSource.FindStringSubmatch(line); len(match) > 1 {
-			currentSource = match[1]
-		}
-		if match := reVersion.FindStringSubmatch(line); len(match) > 1 && currentSource != "" {
-			// Found a pair
-			// Note: This relies on source coming before version or close to it.
-			// And doesn't capture the name "aws" easily without more context.
-			// But we really just need source and version to check updates.
-			
-			foundProviders = append(foundProviders, ProviderInfo{
-				Source:  currentSource,
-				Version: match[1],
-			})
-			currentSource = "" // Reset
-		}
+	found, err := parseRequiredProviders(path, content)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return found, string(content), nil
+}
+
+// moduleFile is a single configuration file of a module together with the
+// provider requirements, module calls and required_version constraints it
+// declares.
+type moduleFile struct {
+	Path             string
+	Content          []byte
+	Providers        []ProviderInfo
+	Modules          []ModuleCall
+	RequiredVersions []coreRequirement
+}
+
+// ParseModule parses every .tf file in dirName and returns the merged list of
+// provider requirements, each tagged with the file it was declared in.
+func (u *Updater) ParseModule(dirName string) ([]ProviderInfo, error) {
+	files, err := loadModule(dirName, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []ProviderInfo
+	for _, f := range files {
+		all = append(all, f.Providers...)
 	}
-	
-	return foundProviders, contentStr, nil
+	return all, nil
 }
 
-func (u *Updater) UpdateProject(dirName string) ([]string, error) {
-	providerPath := filepath.Join(dirName, "provider.tf")
-	if _, err := os.Stat(providerPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("provider.tf not found in %s", dirName)
+// configExts are the extensions of configuration files; .tofu files are
+// only read by OpenTofu but updating them is harmless for Terraform.
+var configExts = []string{".tf", ".tofu"}
+
+// configFiles returns the configuration files of dirName.
+func configFiles(dirName string) ([]string, error) {
+	var paths []string
+	for _, ext := range configExts {
+		matches, err := filepath.Glob(filepath.Join(dirName, "*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
 	}
+	return paths, nil
+}
 
-	providersList, content, err := u.ParseProviderFile(providerPath)
+// loadModule reads and parses the .tf and .tofu files of dirName. Files
+// without a required_providers block, updatable module calls (withModules)
+// or a required_version (withCore) are dropped since there is nothing to
+// update in them.
+func loadModule(dirName string, withModules, withCore bool) ([]*moduleFile, error) {
+	paths, err := configFiles(dirName)
 	if err != nil {
 		return nil, err
 	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no .tf files found in %s", dirName)
+	}
+	sort.Strings(paths)
 
-	var updates []string
-	newContent := content
+	var files []*moduleFile
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
 
-	for _, p := range providersList {
-		latest, err := u.Client.GetLatestVersion(p.Source)
+		found, err := parseRequiredProviders(path, content)
 		if err != nil {
-			// Log error but continue? Or fail?
-			// For CLI, maybe return error
-			return nil, fmt.Errorf("failed to check update for %s: %w", p.Source, err)
-		}
-
-		if latest != p.Version {
-			updates = append(updates, fmt.Sprintf("Updated %s from %s to %s", p.Source, p.Version, latest))
-			// Replace version in content
-			// Be careful to replace only the specific version associated with this source
-			// This regex replacement is naive and might replace other occurrences
-			// A better way is to construct the old string 'version = "old"' and new 'version = "new"'
-			// but indentation matters.
-			
-			// Let's do a scoped replacement if possible, or just global for that version string
-			// if it's unique enough.
-			// Risk: Two providers having same version.
-			
-			// Better: Replace exact line.
-			// re := regexp.MustCompile(fmt.Sprintf(`version\s*=\s*"%s"`, regexp.QuoteMeta(p.Version)))
-			// But we need to make sure it's for the right provider.
-			
-			// For this MVP refactor, we'll assume we replace the specific line following the source.
-			
-			// Let's traverse lines again to reconstruct
-			lines := strings.Split(newContent, "\n")
-			var outLines []string
-			var pendingSource string
-			
-			for _, line := range lines {
-				if match := regexp.MustCompile(`source\s*=\s*"([^"]+)"`).FindStringSubmatch(line); len(match) > 1 {
-					if match[1] == p.Source {
-						pendingSource = p.Source
-					}
-				}
-				
-				if pendingSource == p.Source {
-					if strings.Contains(line, fmt.Sprintf(`version = "%s"`, p.Version)) {
-						line = strings.Replace(line, p.Version, latest, 1)
-						pendingSource = "" // Done for this block
-					}
+			return nil, err
+		}
+		var calls []ModuleCall
+		if withModules {
+			if calls, err = parseModuleCalls(path, content); err != nil {
+				return nil, err
+			}
+		}
+		var core []coreRequirement
+		if withCore {
+			if core, err = parseRequiredVersions(path, content); err != nil {
+				return nil, err
+			}
+		}
+		if len(found) == 0 && len(calls) == 0 && len(core) == 0 {
+			continue
+		}
+
+		for i := range found {
+			found[i].File = path
+		}
+		for i := range calls {
+			calls[i].File = path
+		}
+		files = append(files, &moduleFile{Path: path, Content: content, Providers: found, Modules: calls, RequiredVersions: core})
+	}
+
+	if len(files) == 0 {
+		if withModules {
+			return nil, fmt.Errorf("%w (nor module blocks) in %s", ErrNoRequiredProviders, dirName)
+		}
+		return nil, fmt.Errorf("%w in %s", ErrNoRequiredProviders, dirName)
+	}
+
+	return files, nil
+}
+
+// UpdateProject rewrites the provider constraints of the module in dirName,
+// and with Modules the versions of the modules it calls. Providers and
+// modules that cannot be checked are recorded in the result's Failed list;
+// canceling ctx stops the update with ctx's error.
+func (u *Updater) UpdateProject(ctx context.Context, dirName string) (ModuleResult, error) {
+	result := ModuleResult{Path: dirName}
+
+	files, err := loadModule(dirName, u.Modules, u.RequiredVersion)
+	if err != nil {
+		return result, err
+	}
+
+	// constraints tracks every constraint declared per source after the
+	// update; changed marks the sources whose lock entry must follow.
+	constraints := make(map[string][]string)
+	changed := make(map[string]bool)
+
+	u.prefetch(ctx, files)
+
+	var coreEdits map[string][]versionEdit
+	var floor semver.Version
+	hasFloor := false
+	if u.RequiredVersion {
+		coreEdits, floor, hasFloor, err = u.updateRequiredVersions(ctx, files, &result)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	for _, f := range files {
+		edits := coreEdits[f.Path]
+
+		for _, p := range f.Providers {
+			// Without a version argument there is nothing to rewrite.
+			if p.Version == "" {
+				continue
+			}
+
+			parsed, err := semver.ParseConstraints(p.Version)
+			if err != nil {
+				return result, fmt.Errorf("%s: provider %s: %w", f.Path, p.Source, err)
+			}
+
+			versions, err := u.availableVersions(ctx, p.Source)
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			if err != nil {
+				result.Failed = append(result.Failed, Failure{
+					Kind:   KindProvider,
+					Module: dirName,
+					File:   f.Path,
+					Line:   p.versionRange.Start.Line,
+					Name:   p.Name,
+					Source: p.Source,
+					Err:    err,
+				})
+				constraints[p.Source] = append(constraints[p.Source], p.Version)
+				continue
+			}
+
+			policy := u.policyFor(p.Source, p.Name)
+			candidates := versions
+			if hasFloor {
+				candidates = u.runnable(p.Source, versions, floor)
+			}
+			picked, newest, ok := selectVersion(candidates, parsed, policy)
+			if !ok {
+				continue
+			}
+
+			if picked.LessThan(newest) {
+				result.Skipped = append(result.Skipped, Skip{
+					Kind:       KindProvider,
+					Module:     dirName,
+					File:       f.Path,
+					Line:       p.versionRange.Start.Line,
+					Name:       p.Name,
+					Source:     p.Source,
+					Constraint: p.Version,
+					Latest:     newest.String(),
+					Policy:     policy,
+					Major:      newest.Major > picked.Major,
+				})
+			}
+			if unheld, _, _ := selectVersion(versions, parsed, policy); picked.LessThan(unheld) {
+				// Only runnable releases were candidates.
+				result.Skipped = append(result.Skipped, Skip{
+					Kind:       KindProvider,
+					Module:     dirName,
+					File:       f.Path,
+					Line:       p.versionRange.Start.Line,
+					Name:       p.Name,
+					Source:     p.Source,
+					Constraint: p.Version,
+					Latest:     unheld.String(),
+					Policy:     policy,
+					Major:      unheld.Major > picked.Major,
+					Core:       u.Flavor.Command() + " " + floor.String(),
+				})
+			}
+
+			next, bumped := parsed.Bump(picked)
+			if !bumped {
+				constraints[p.Source] = append(constraints[p.Source], p.Version)
+				continue
+			}
+			constraints[p.Source] = append(constraints[p.Source], next)
+			changed[p.Source] = true
+
+			result.Changes = append(result.Changes, Change{
+				Kind:   KindProvider,
+				Module: dirName,
+				File:   f.Path,
+				Line:   p.versionRange.Start.Line,
+				Name:   p.Name,
+				Source: p.Source,
+				From:   p.Version,
+				To:     next,
+				Latest: newest.String(),
+			})
+			edits = append(edits, versionEdit{Range: p.versionRange, Version: next})
+		}
+
+		moduleEdits, err := u.updateModuleCalls(ctx, f, &result)
+		if err != nil {
+			return result, err
+		}
+		edits = append(edits, moduleEdits...)
+
+		if len(edits) == 0 {
+			continue
+		}
+
+		updated := rewriteVersions(f.Content, edits)
+		result.Files = append(result.Files, FileChange{Path: f.Path, Before: f.Content, After: updated})
+		if u.DryRun {
+			continue
+		}
+		if err := os.WriteFile(f.Path, updated, 0644); err != nil {
+			return result, err
+		}
+	}
+
+	if u.Lock && len(changed) > 0 {
+		lockConstraints := make(map[string][]string)
+		for source := range changed {
+			lockConstraints[source] = constraints[source]
+		}
+
+		lock, failed, err := u.syncLockFile(ctx, dirName, lockConstraints)
+		if err != nil {
+			return result, fmt.Errorf("failed to update %s: %w", lockfile.FileName, err)
+		}
+		result.Failed = append(result.Failed, failed...)
+		if lock != nil {
+			result.Files = append(result.Files, *lock)
+			if !u.DryRun {
+				if err := os.WriteFile(lock.Path, lock.After, 0644); err != nil {
+					return result, err
 				}
-				outLines = append(outLines, line)
 			}
-			newContent = strings.Join(outLines, "\n")
 		}
 	}
 
-	if len(updates) > 0 {
-		if err := os.WriteFile(providerPath, []byte(newContent), 0644); err != nil {
-			return nil, err
+	return result, nil
+}
+
+// policyFor returns the policy of a provider or module by its source or
+// local name.
+func (u *Updater) policyFor(source, name string) Policy {
+	if policy, ok := u.Overrides[source]; ok {
+		return policy
+	}
+	if policy, ok := u.Overrides[name]; ok {
+		return policy
+	}
+	if u.Policy == "" {
+		return AllowMajor
+	}
+	return u.Policy
+}
+
+// availableVersions returns the published releases of source sorted
+// ascending, querying the registry only the first time a source is seen.
+func (u *Updater) availableVersions(ctx context.Context, source string) ([]semver.Version, error) {
+	l := u.lookup(source, func() lookup {
+		// Prereleases are kept so that constraints pinning one still resolve;
+		// selectVersion never picks them as upgrade targets.
+		published, err := u.Client.ListVersions(ctx, source, true)
+		if err != nil {
+			return lookup{err: err}
+		}
+		versions := make([]semver.Version, len(published))
+		protocols := make(map[string][]string)
+		for i, p := range published {
+			versions[i] = p.Version
+			protocols[p.Version.String()] = p.Protocols
 		}
+		return lookup{versions: versions, protocols: protocols}
+	})
+	return l.versions, l.err
+}
+
+// lookup returns the outcome of the query named key, running fetch only
+// the first time key is seen.
+func (u *Updater) lookup(key string, fetch func() lookup) lookup {
+	u.mu.Lock()
+	l, ok := u.versions[key]
+	u.mu.Unlock()
+	if ok {
+		return l
 	}
 
-	return updates, nil
+	l = fetch()
+
+	u.mu.Lock()
+	if u.versions == nil {
+		u.versions = make(map[string]lookup)
+	}
+	u.versions[key] = l
+	u.mu.Unlock()
+	return l
+}
+
+// prefetch runs the queries of the providers and module calls of files
+// concurrently, as far as the client's MaxConcurrent allows, so that
+// UpdateProject finds them cached.
+func (u *Updater) prefetch(ctx context.Context, files []*moduleFile) {
+	var wg sync.WaitGroup
+	seen := make(map[string]bool)
+	run := func(key string, query func()) {
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			query()
+		}()
+	}
+
+	for _, f := range files {
+		for _, p := range f.Providers {
+			if p.Version != "" {
+				run(p.Source, func() { u.availableVersions(ctx, p.Source) })
+			}
+		}
+		for _, m := range f.Modules {
+			run(m.lookupKey(), func() { u.moduleVersions(ctx, m) })
+		}
+		if len(f.RequiredVersions) > 0 {
+			run("core", func() { u.coreVersions(ctx) })
+		}
+	}
+	wg.Wait()
 }
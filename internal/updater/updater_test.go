@@ -1,9 +1,18 @@
 package updater
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+
+	"warike/base/internal/providers"
 )
 
 func TestParseProviderFile(t *testing.T) {
@@ -39,23 +48,446 @@ terraform {
 	if providers[0].Version != "4.10.0" {
 		t.Errorf("Expected version 4.10.0, got %s", providers[0].Version)
 	}
+	if providers[0].Name != "aws" {
+		t.Errorf("Expected name aws, got %s", providers[0].Name)
+	}
+}
+
+func TestParseProviderFile_HandWritten(t *testing.T) {
+	content := `
+terraform {
+  required_providers {
+    # Pinned until the v6 migration lands.
+    aws = { version = "4.10.0", source = "hashicorp/aws" }
+    github = {
+      version = "5.0.0" // keep in sync with CI
+      source  = "integrations/github"
+    }
+    random = {
+      source  = "hashicorp/random"
+      version = <<EOT
+3.5.1
+EOT
+    }
+    null = "~> 3.0"
+  }
+}
+`
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "provider.tf")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	u := NewUpdater()
+	got, _, err := u.ParseProviderFile(filePath)
+	if err != nil {
+		t.Fatalf("ParseProviderFile failed: %v", err)
+	}
+
+	want := []ProviderInfo{
+		{Name: "aws", Source: "hashicorp/aws", Version: "4.10.0"},
+		{Name: "github", Source: "integrations/github", Version: "5.0.0"},
+		{Name: "random", Source: "hashicorp/random", Version: "3.5.1"},
+		{Name: "null", Source: "hashicorp/null", Version: "~> 3.0"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d providers, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].Name != want[i].Name || got[i].Source != want[i].Source || got[i].Version != want[i].Version {
+			t.Errorf("provider %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestUpdateProject_PreservesFormatting(t *testing.T) {
+	content := `terraform {
+  required_providers {
+    # Pinned until the v6 migration lands.
+    aws = { version = "4.10.0", source = "hashicorp/aws" }
+    random = {
+      source  = "hashicorp/random"
+      version = <<EOT
+3.5.1
+EOT
+    }
+  }
+}
+`
+	want := `terraform {
+  required_providers {
+    # Pinned until the v6 migration lands.
+    aws = { version = "5.30.0", source = "hashicorp/aws" }
+    random = {
+      source  = "hashicorp/random"
+      version = "5.30.0"
+    }
+  }
+}
+`
+	u, _ := newTestUpdater(t, "3.5.1", "4.10.0", "5.30.0")
+
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "provider.tf")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := u.UpdateProject(t.Context(), tmpDir)
+	if err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	if len(result.Changes) != 2 {
+		t.Errorf("Expected 2 updates, got %d: %v", len(result.Changes), result.Changes)
+	}
+
+	got, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("Unexpected rewrite:\n%s\nwant:\n%s", got, want)
+	}
 }
 
-func TestUpdateProject_MissingFile(t *testing.T) {
+func TestUpdateProject_NoConfiguration(t *testing.T) {
 	u := NewUpdater()
 	tmpDir := t.TempDir()
 	
-	_, err := u.UpdateProject(tmpDir)
+	_, err := u.UpdateProject(t.Context(), tmpDir)
 	if err == nil {
-		t.Error("Expected error for missing provider.tf, got nil")
+		t.Error("Expected error for a directory without .tf files, got nil")
 	}
 	
-	expected := "provider.tf not found"
+	expected := "no .tf files found"
 	if err != nil && err.Error() != expected && !contains(err.Error(), expected) {
 		t.Errorf("Expected error containing %q, got %q", expected, err.Error())
 	}
+
+	// A module whose files declare no providers is reported as such.
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.tf"), []byte("# main.tf\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = u.UpdateProject(t.Context(), tmpDir)
+	expected = "no required_providers found"
+	if err == nil || !contains(err.Error(), expected) {
+		t.Errorf("Expected error containing %q, got %v", expected, err)
+	}
+}
+
+func TestUpdateProject_ScansAllFiles(t *testing.T) {
+	files := map[string]string{
+		"versions.tf": `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "4.0.0"
+    }
+  }
+}
+`,
+		"terraform.tf": `terraform {
+  required_providers {
+    aws = {
+      source = "hashicorp/aws"
+      version = "4.0.0"
+    }
+  }
+}
+
+terraform {
+  required_providers {
+    github = {
+      source  = "integrations/github"
+      version = "5.30.0"
+    }
+  }
+}
+`,
+		"main.tf": "# main.tf\n",
+	}
+
+	u, requests := newTestUpdater(t, "4.0.0", "5.30.0")
+
+	tmpDir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, err := u.ParseModule(tmpDir)
+	if err != nil {
+		t.Fatalf("ParseModule failed: %v", err)
+	}
+	if len(found) != 3 {
+		t.Fatalf("Expected 3 provider requirements, got %d", len(found))
+	}
+	if filepath.Base(found[2].File) != "versions.tf" {
+		t.Errorf("Expected last requirement to come from versions.tf, got %s", found[2].File)
+	}
+
+	result, err := u.UpdateProject(t.Context(), tmpDir)
+	if err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	if len(result.Changes) != 2 {
+		t.Errorf("Expected 2 updates, got %d: %v", len(result.Changes), result.Changes)
+	}
+	if *requests != 2 {
+		t.Errorf("Expected one registry lookup per source, got %d", *requests)
+	}
+
+	for _, name := range []string{"versions.tf", "terraform.tf"} {
+		got, err := os.ReadFile(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(got), `version = "5.30.0"`) || strings.Contains(string(got), "4.0.0") {
+			t.Errorf("%s was not updated:\n%s", name, got)
+		}
+	}
+}
+
+// newTestUpdater returns an Updater backed by a stand-in registry that
+// publishes the given versions for every provider. The returned counter
+// tracks how many registry requests were made.
+func newTestUpdater(t *testing.T, versions ...string) (*Updater, *int) {
+	t.Helper()
+
+	requests := 0
+	type entry struct {
+		Version   string   `json:"version"`
+		Protocols []string `json:"protocols"`
+	}
+	var list struct {
+		Versions []entry `json:"versions"`
+	}
+	for _, v := range versions {
+		list.Versions = append(list.Versions, entry{Version: v, Protocols: []string{"5.0"}})
+	}
+	body, err := json.Marshal(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Providers of a module are looked up concurrently.
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	u := NewUpdater()
+	u.Client = &providers.Client{BaseURL: server.URL, HTTPClient: server.Client()}
+	return u, &requests
 }
 
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && s[0:len(substr)] == substr
 }
+
+func TestUpdateProject_KeepsConstraintStyle(t *testing.T) {
+	content := `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.30"
+    }
+    google = {
+      source  = "hashicorp/google"
+      version = ">= 4.0, < 6.0"
+    }
+    random = {
+      source  = "hashicorp/random"
+      version = ">= 3.0"
+    }
+  }
+}
+`
+	u, _ := newTestUpdater(t, "3.0.0", "4.0.0", "5.30.0", "5.31.0", "6.2.0")
+
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "versions.tf")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := u.UpdateProject(t.Context(), tmpDir)
+	if err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	if len(result.Changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %d: %v", len(result.Changes), result.Changes)
+	}
+
+	got, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`version = "~> 6.2"`, `version = ">= 4.0, < 7.0"`, `version = ">= 3.0"`} {
+		if !strings.Contains(string(got), s) {
+			t.Errorf("Expected rewritten file to contain %s:\n%s", s, got)
+		}
+	}
+}
+
+func TestUpdateProject_DryRun(t *testing.T) {
+	u, _ := newTestUpdater(t, "4.0.0", "5.0.0")
+	u.DryRun = true
+
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "provider.tf")
+	content := []byte(`terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "4.0.0"
+    }
+  }
+}
+`)
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := u.UpdateProject(t.Context(), tmpDir)
+	if err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	if len(result.Files) != 1 {
+		t.Fatalf("Expected 1 changed file, got %d", len(result.Files))
+	}
+	if !strings.Contains(string(result.Files[0].After), `version = "5.0.0"`) {
+		t.Errorf("Expected new content to carry 5.0.0:\n%s", result.Files[0].After)
+	}
+
+	got, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(content) {
+		t.Error("Dry run modified the file on disk")
+	}
+}
+
+func TestUpdateProject_PrivateRegistry(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"providers.v1": "/api/registry/v1/providers/"}`))
+	})
+	mux.HandleFunc("/api/registry/v1/providers/acme/internal/versions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"versions": [{"version": "1.2.0"}, {"version": "1.3.0"}]}`))
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	tmpDir := t.TempDir()
+	writeModule(t, tmpDir, `terraform {
+  required_providers {
+    internal = {
+      source  = "`+host+`/acme/internal"
+      version = "~> 1.2.0"
+    }
+  }
+}
+`)
+
+	u := NewUpdater()
+	u.Client = &providers.Client{
+		BaseURL:     providers.DefaultRegistryURL,
+		HTTPClient:  server.Client(),
+		Credentials: providers.Credentials{host: "secret"},
+	}
+
+	result, err := u.UpdateProject(t.Context(), tmpDir)
+	if err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	if len(result.Changes) != 1 || result.Changes[0].To != "~> 1.3.0" {
+		t.Fatalf("Expected the private provider to be bumped to ~> 1.3.0, got %+v", result.Changes)
+	}
+}
+
+func TestUpdateProject_PartialFailure(t *testing.T) {
+	content := `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.30"
+    }
+    github = {
+      source  = "integrations/github"
+      version = "~> 6.0"
+    }
+  }
+}
+`
+	attempts := 0
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "github") {
+			mu.Lock()
+			attempts++
+			mu.Unlock()
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"versions": [{"version": "5.30.0"}, {"version": "5.31.0"}]}`))
+	}))
+	defer server.Close()
+
+	u := NewUpdater()
+	u.Client = &providers.Client{BaseURL: server.URL, HTTPClient: server.Client(), MaxRetries: 2}
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "versions.tf"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := u.UpdateProject(t.Context(), tmpDir)
+	if err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	if len(result.Changes) != 1 || result.Changes[0].Source != "hashicorp/aws" {
+		t.Errorf("Expected hashicorp/aws to be updated, got %v", result.Changes)
+	}
+	if len(result.Failed) != 1 || result.Failed[0].Source != "integrations/github" {
+		t.Fatalf("Expected integrations/github to fail, got %v", result.Failed)
+	}
+	if !strings.Contains(result.Failed[0].Err.Error(), "after 3 attempts") {
+		t.Errorf("Failure = %v", result.Failed[0].Err)
+	}
+	if attempts != 3 {
+		t.Errorf("Registry was asked %d times for the failing provider, want 3", attempts)
+	}
+}
+
+func TestUpdateProject_Canceled(t *testing.T) {
+	u, _ := newTestUpdater(t, "5.31.0")
+
+	tmpDir := t.TempDir()
+	content := "terraform {\n  required_providers {\n    aws = {\n      source  = \"hashicorp/aws\"\n      version = \"~> 5.30\"\n    }\n  }\n}\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "versions.tf"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	result, err := u.UpdateProject(ctx, tmpDir)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("UpdateProject() error = %v, want %v", err, context.Canceled)
+	}
+	if len(result.Failed) != 0 || len(result.Changes) != 0 {
+		t.Errorf("UpdateProject() = %+v, want no failures or changes", result)
+	}
+}
@@ -1,28 +1,57 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-isatty"
+	"warike/base/internal/catalog"
+	"warike/base/internal/config"
+	"warike/base/internal/diff"
+	"warike/base/internal/flavor"
+	"warike/base/internal/generator"
+	"warike/base/internal/providers"
+	"warike/base/internal/report"
 	"warike/base/internal/ui"
 	"warike/base/internal/updater"
 )
 
 func main() {
-	if len(os.Args) < 2 {
+	global := flag.NewFlagSet("tfinit", flag.ExitOnError)
+	global.Usage = printHelp
+	timeout := global.Duration("timeout", 0, "Give up on registry requests after this long, e.g. 30s (default no limit)")
+	global.Parse(os.Args[1:])
+
+	if global.NArg() < 1 {
 		printHelp()
 		os.Exit(1)
 	}
 
-	cmd := os.Args[1]
+	cmd := global.Arg(0)
+	args := global.Args()[1:]
 	
 	switch cmd {
 	case "create":
-		handleCreate(os.Args[2:])
+		handleCreate(args, *timeout)
 	case "update":
-		handleUpdate(os.Args[2:])
+		handleUpdate(args, *timeout)
+	case "add":
+		handleAdd(args, *timeout)
+	case "remove":
+		handleRemove(args)
 	default:
 		fmt.Printf("Unknown command: %s\n", cmd)
 		printHelp()
@@ -30,17 +59,36 @@ func main() {
 	}
 }
 
-func handleCreate(args []string) {
+func handleCreate(args []string, timeout time.Duration) {
 	createCmd := flag.NewFlagSet("create", flag.ExitOnError)
 	nameFlag := createCmd.String("name", "", "Name of the project directory (optional, positional argument takes precedence)")
+	var providerNames stringList
+	createCmd.Var(&providerNames, "providers", "Providers to include, e.g. aws,github (preselected in the TUI, required with --yes)")
+	aliases := aliasList{}
+	createCmd.Var(aliases, "alias", "Aliased provider configuration as provider.alias[,setting=value...], e.g. aws.us_east_1,region=us-east-1 (repeatable)")
+	yes := createCmd.Bool("yes", false, "Generate the files without launching the interactive UI")
+	var catalogDirs stringList
+	createCmd.Var(&catalogDirs, "catalog", "Extra directory of provider definition files (repeatable)")
+	var templates stringList
+	createCmd.Var(&templates, "template", "Directory or git URL of .tmpl files overriding the built-in templates (repeatable, first wins)")
+	backendName := createCmd.String("backend", "", "State backend: none, s3, s3-dynamodb, gcs, azurerm, cloud, http or local (asked in the TUI when unset)")
+	var backendConfig stringList
+	createCmd.Var(&backendConfig, "backend-config", "Backend setting as key=value, e.g. bucket=acme-state (repeatable)")
+	var envs stringList
+	createCmd.Var(&envs, "envs", "Environments of the project, e.g. dev,staging,prod (default dev)")
+	layoutName := createCmd.String("layout", "", "Environment layout: single, tfvars, dirs or workspaces")
+	flavorName := createCmd.String("flavor", "", "terraform or tofu: the registry queried and the constructs generated (default terraform)")
+	encryption := createCmd.Bool("encryption", false, "Scaffold OpenTofu state and plan encryption (tofu flavor)")
+	tofuFiles := createCmd.Bool("tofu-files", false, "Write .tofu instead of .tf files (tofu flavor)")
+	createClient := addClientFlags(createCmd, timeout)
 
-	createCmd.Parse(args)
+	args = parseArgs(createCmd, args)
 
 	targetDir := *nameFlag
 
 	// If a positional argument is provided, it's our target directory.
-	if createCmd.NArg() > 0 {
-		targetDir = createCmd.Arg(0)
+	if len(args) > 0 {
+		targetDir = args[0]
 	}
 
 	// Default to current directory if no flag or argument is provided.
@@ -48,61 +96,864 @@ func handleCreate(args []string) {
 		targetDir = "."
 	}
 
-	// Validate the target directory.
-	if targetDir != "." {
-		info, err := os.Stat(targetDir)
-		if err == nil && info.IsDir() {
-			// Directory exists, check if it's empty.
-			entries, readErr := os.ReadDir(targetDir)
-			if readErr != nil {
-				fmt.Printf("Error reading directory '%s': %v\n", targetDir, readErr)
-				os.Exit(1)
-			}
-			if len(entries) > 0 {
-				fmt.Printf("Error: directory '%s' already exists and is not empty\n", targetDir)
-				os.Exit(1)
+	if err := checkTargetDir(targetDir); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cat, err := catalog.Load(append(catalog.UserDirs(), catalogDirs...)...)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	backend, err := parseBackend(*backendName, backendConfig)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	layout, err := generator.ParseLayout(*layoutName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	f, err := flavor.Parse(*flavorName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	client, err := createClient.newClient(f)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := createClient.context()
+	defer cancel()
+
+	templateDirs, cleanup, err := resolveTemplates(ctx, templates)
+	if err != nil {
+		fmt.Printf("Error: %v\n", contextError(ctx, err))
+		os.Exit(1)
+	}
+
+	opts := createOptions{
+		Providers:    providerNames,
+		Aliases:      aliases,
+		TemplateDirs: templateDirs,
+		Backend:      backend,
+		Environments: envs,
+		Layout:       layout,
+		Flavor:       f,
+		Encryption:   *encryption,
+		TofuFiles:    *tofuFiles,
+	}
+
+	// Scripts and pipelines have no terminal to drive the TUI with.
+	if *yes || !isatty.IsTerminal(os.Stdin.Fd()) {
+		err := createProject(ctx, client, cat, targetDir, opts)
+		cleanup()
+		if err != nil {
+			fmt.Printf("Error: %v\n", contextError(ctx, err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	m := ui.NewModel(ctx, targetDir, cat)
+	m.TemplateDirs = templateDirs
+	m.Backend = backend
+	m.Environments = envs
+	m.Layout = layout
+	m.Client = client
+	m.Cancel = cancel
+	m.Flavor = f
+	m.Encryption = *encryption
+	m.TofuFiles = *tofuFiles
+	// The backend step only asks what the flags left open.
+	m.ChooseBackend = *backendName == ""
+	for i, p := range m.Providers {
+		for _, name := range providerNames {
+			if p.Name == name {
+				m.Selected[i] = true
 			}
 		}
-		// os.IsNotExist(err) is the happy path for a new directory, so we don't check for it.
+		if a, ok := aliases[p.Name]; ok {
+			m.Providers[i].Aliases = a
+			m.Selected[i] = true
+		}
 	}
 
-	p := tea.NewProgram(ui.InitialModel(targetDir))
-	if _, err := p.Run(); err != nil {
+	p := tea.NewProgram(m)
+	_, err = p.Run()
+	cleanup()
+	if err != nil {
 		fmt.Printf("Alas, there's been an error: %v", err)
 		os.Exit(1)
 	}
 }
 
-func handleUpdate(args []string) {
+// parseArgs parses the flags of fs wherever they appear in args, before or
+// after the positional arguments, and returns the positional arguments.
+// Everything after "--" is positional.
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional
+		}
+		if len(rest) < len(args) && args[len(args)-len(rest)-1] == "--" {
+			return append(positional, rest...)
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// resolveTemplates turns --template arguments into local directories,
+// cloning git URLs. cleanup removes the clones.
+func resolveTemplates(ctx context.Context, specs []string) (dirs []string, cleanup func(), err error) {
+	var cleanups []func()
+	cleanup = func() {
+		for _, c := range cleanups {
+			c()
+		}
+	}
+	for _, spec := range specs {
+		dir, c, err := generator.ResolveTemplateDir(ctx, spec)
+		if err != nil {
+			cleanup()
+			return nil, func() {}, err
+		}
+		cleanups = append(cleanups, c)
+		dirs = append(dirs, dir)
+	}
+	return dirs, cleanup, nil
+}
+
+// checkTargetDir refuses to scaffold into an existing directory that already
+// has content.
+func checkTargetDir(targetDir string) error {
+	if targetDir == "." {
+		return nil
+	}
+
+	info, err := os.Stat(targetDir)
+	if err == nil && info.IsDir() {
+		// Directory exists, check if it's empty.
+		entries, readErr := os.ReadDir(targetDir)
+		if readErr != nil {
+			return fmt.Errorf("reading directory '%s': %w", targetDir, readErr)
+		}
+		if len(entries) > 0 {
+			return fmt.Errorf("directory '%s' already exists and is not empty", targetDir)
+		}
+	}
+	// os.IsNotExist(err) is the happy path for a new directory, so we don't check for it.
+	return nil
+}
+
+// parseBackend builds the backend of the --backend and --backend-config
+// flags. Settings without a backend keep the backend open for the TUI.
+func parseBackend(name string, settings []string) (*generator.Backend, error) {
+	backend, err := generator.ParseBackend(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(settings) == 0 {
+		return backend, nil
+	}
+	if backend == nil {
+		if name != "" {
+			return nil, fmt.Errorf("--backend-config needs a backend other than %s", name)
+		}
+		backend = &generator.Backend{}
+	}
+
+	backend.Config = make(map[string]string)
+	for _, kv := range settings {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --backend-config %q, want key=value", kv)
+		}
+		backend.Config[key] = value
+	}
+	return backend, nil
+}
+
+// createOptions are the create flags that shape the generated files.
+type createOptions struct {
+	Providers    []string
+	Aliases      aliasList
+	TemplateDirs []string
+	Backend      *generator.Backend
+	Environments []string
+	Layout       string
+	Flavor       flavor.Flavor
+	Encryption   bool
+	TofuFiles    bool
+}
+
+// createProject generates a project without any interaction: it resolves the
+// named providers in the catalog, fetches their latest versions and writes
+// the same files the TUI would, using the template directories ahead of the
+// built-in templates.
+func createProject(ctx context.Context, client *providers.Client, cat *catalog.Catalog, targetDir string, opts createOptions) error {
+	names := opts.Providers
+	if len(names) == 0 {
+		return fmt.Errorf("no providers selected; pass --providers (e.g. --providers aws,github) when running non-interactively")
+	}
+
+	selected, err := catalogProviders(cat, names)
+	if err != nil {
+		return err
+	}
+	for name := range opts.Aliases {
+		if !slices.Contains(names, name) {
+			return fmt.Errorf("--alias for %s, which is not among the providers", name)
+		}
+	}
+	for i, p := range selected {
+		selected[i].Aliases = opts.Aliases[p.Name]
+	}
+
+	selected, err = ui.FetchVersions(ctx, client, selected)
+	if err != nil {
+		return err
+	}
+
+	required, err := ui.FetchRequiredVersion(ctx, client, opts.Flavor)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		fmt.Printf("Warning: %v; leaving out required_version\n", err)
+	}
+
+	data := ui.GeneratorData(targetDir, selected)
+	data.RequiredVersion = required
+	if opts.Backend != nil && opts.Backend.Type != "" {
+		data.Backend = opts.Backend
+	}
+	data.Environments = opts.Environments
+	data.Layout = opts.Layout
+	data.Flavor = opts.Flavor
+	data.Encryption = opts.Encryption
+	data.TofuFiles = opts.TofuFiles
+	if err := generator.WriteProject(targetDir, data, opts.TemplateDirs...); err != nil {
+		return err
+	}
+
+	fmt.Printf("Terraform files generated in %s\n", targetDir)
+	for _, p := range selected {
+		fmt.Printf("  %s %s\n", p.Source, p.LatestVersion)
+	}
+	return nil
+}
+
+// stringList is a flag.Value collecting comma separated values across
+// repeated flags.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+func handleAdd(args []string, timeout time.Duration) {
+	addCmd := flag.NewFlagSet("add", flag.ExitOnError)
+	name := addCmd.String("name", ".", "Name of the project directory to add the providers to")
+	var catalogDirs stringList
+	addCmd.Var(&catalogDirs, "catalog", "Extra directory of provider definition files (repeatable)")
+	flavorName := addCmd.String("flavor", "", "terraform or tofu: the registry queried (default terraform)")
+	addClient := addClientFlags(addCmd, timeout)
+
+	names := parseArgs(addCmd, args)
+
+	if len(names) == 0 {
+		fmt.Println("Error: no providers given, e.g. tfinit add cloudflare")
+		os.Exit(1)
+	}
+
+	cat, err := catalog.Load(append(catalog.UserDirs(), catalogDirs...)...)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	f, err := flavor.Parse(*flavorName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	client, err := addClient.newClient(f)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := addClient.context()
+	defer cancel()
+
+	if err := addProviders(ctx, client, cat, *name, names); err != nil {
+		fmt.Printf("Error: %v\n", contextError(ctx, err))
+		os.Exit(1)
+	}
+	fmt.Printf("Run %s init to install the new providers.\n", f.Command())
+}
+
+// addProviders adds the named catalog providers, at their latest version, to
+// the project in dir.
+func addProviders(ctx context.Context, client *providers.Client, cat *catalog.Catalog, dir string, names []string) error {
+	selected, err := catalogProviders(cat, names)
+	if err != nil {
+		return err
+	}
+	selected, err = ui.FetchVersions(ctx, client, selected)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range selected {
+		changed, err := generator.AddProvider(dir, generator.ProviderConfig{
+			Name:          p.Name,
+			Source:        p.Source,
+			LatestVersion: p.LatestVersion,
+			Definition:    p.Definition,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Added %s %s to %s\n", p.Source, p.LatestVersion, strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+func handleRemove(args []string) {
+	removeCmd := flag.NewFlagSet("remove", flag.ExitOnError)
+	name := removeCmd.String("name", ".", "Name of the project directory to remove the providers from")
+	var catalogDirs stringList
+	removeCmd.Var(&catalogDirs, "catalog", "Extra directory of provider definition files (repeatable)")
+
+	names := parseArgs(removeCmd, args)
+
+	if len(names) == 0 {
+		fmt.Println("Error: no providers given, e.g. tfinit remove vercel")
+		os.Exit(1)
+	}
+
+	cat, err := catalog.Load(append(catalog.UserDirs(), catalogDirs...)...)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := removeProviders(cat, *name, names); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// removeProviders removes the named catalog providers from the project in
+// dir, refusing to remove one that is still in use.
+func removeProviders(cat *catalog.Catalog, dir string, names []string) error {
+	selected, err := catalogProviders(cat, names)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range selected {
+		changed, err := generator.RemoveProvider(dir, generator.ProviderConfig{
+			Name:       p.Name,
+			Source:     p.Source,
+			Definition: p.Definition,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s from %s\n", p.Source, strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+// catalogProviders resolves provider names in the catalog.
+func catalogProviders(cat *catalog.Catalog, names []string) ([]ui.Provider, error) {
+	var selected []ui.Provider
+	for _, name := range names {
+		def, ok := cat.Get(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown provider %q (available: %s)", name, strings.Join(cat.Names(), ", "))
+		}
+		selected = append(selected, ui.Provider{Name: def.Name, Source: def.Source, Definition: def})
+	}
+	return selected, nil
+}
+
+// aliasList is a flag.Value collecting provider aliases by provider name
+// across repeated flags. Values are not split on commas, which separate the
+// alias settings.
+type aliasList map[string][]generator.Alias
+
+func (l aliasList) String() string {
+	var specs []string
+	for provider, aliases := range l {
+		for _, a := range aliases {
+			specs = append(specs, provider+"."+a.String())
+		}
+	}
+	sort.Strings(specs)
+	return strings.Join(specs, " ")
+}
+
+func (l aliasList) Set(value string) error {
+	provider, a, err := generator.ParseAlias(value)
+	if err != nil {
+		return err
+	}
+	l[provider] = append(l[provider], a)
+	return nil
+}
+
+func handleUpdate(args []string, timeout time.Duration) {
 	updateCmd := flag.NewFlagSet("update", flag.ExitOnError)
 	name := updateCmd.String("name", ".", "Name of the project directory to update")
+	recursive := updateCmd.Bool("recursive", false, "Update every module found below the directory")
+	var ignore stringList
+	updateCmd.Var(&ignore, "ignore", "Glob of directories to skip in recursive mode (repeatable, comma separated)")
+	allow := updateCmd.String("allow", "", "Largest version bump allowed: patch, minor or major (default major)")
+	configPath := updateCmd.String("config", "", "Path to the settings file (defaults to <dir>/"+config.DefaultFile+")")
+	dryRun := updateCmd.Bool("dry-run", false, "Print a diff of the changes without writing them; exits with status 2 if updates are available")
+	output := updateCmd.String("output", "text", "Output format: text, json or sarif")
+	lock := updateCmd.Bool("lock", true, "Update .terraform.lock.hcl entries of updated providers")
+	modules := updateCmd.Bool("modules", false, "Also update registry module versions and git module version tags")
+	requiredVersion := updateCmd.Bool("required-version", false, "Raise the floor of required_version constraints and hold back providers it cannot run")
+	var platforms stringList
+	updateCmd.Var(&platforms, "platform", "os_arch to hash into the lock file (repeatable, comma separated; defaults to the current platform)")
+	flavorName := updateCmd.String("flavor", "", "terraform or tofu: the registry queried and the lock file host (default terraform)")
+	updateClient := addClientFlags(updateCmd, timeout)
 	
-	updateCmd.Parse(args)
+	args = parseArgs(updateCmd, args)
 	
 	targetDir := *name
+	if len(args) > 0 {
+		targetDir = args[0]
+	}
 	if targetDir == "" {
 		targetDir = "."
 	}
-	
-	u := updater.NewUpdater()
-	updates, err := u.UpdateProject(targetDir)
+
+	switch *output {
+	case "text", "json", "sarif":
+	default:
+		fmt.Printf("Error: unknown output format %q (want text, json or sarif)\n", *output)
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig(*configPath, targetDir)
 	if err != nil {
-		fmt.Printf("Error updating project: %v\n", err)
+		fmt.Printf("Error reading config: %v\n", err)
 		os.Exit(1)
 	}
 	
-	if len(updates) == 0 {
-		fmt.Println("No updates available.")
+	u, err := newUpdater(cfg, *allow, *flavorName, updateClient, platforms)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	u.DryRun = *dryRun
+	u.Lock = *lock
+	u.Modules = *modules
+	u.RequiredVersion = *requiredVersion
+
+	ctx, cancel := updateClient.context()
+	defer cancel()
+
+	var results []updater.ModuleResult
+	// Modules that failed in recursive mode are reported once the others,
+	// which may already have been written, are.
+	var moduleErr error
+	if *recursive {
+		results, moduleErr = u.UpdateRecursive(ctx, targetDir, append(cfg.Ignore, ignore...))
+		if moduleErr != nil && len(results) == 0 {
+			fmt.Printf("Error updating modules: %v\n", contextError(ctx, moduleErr))
+			os.Exit(1)
+		}
 	} else {
-		for _, update := range updates {
-			fmt.Println(update)
+		result, err := u.UpdateProject(ctx, targetDir)
+		if err != nil {
+			fmt.Printf("Error updating project: %v\n", contextError(ctx, err))
+			os.Exit(1)
+		}
+		results = []updater.ModuleResult{result}
+	}
+
+	switch *output {
+	case "json", "sarif":
+		rep := report.New(results, *dryRun)
+		write := rep.WriteJSON
+		if *output == "sarif" {
+			write = rep.WriteSARIF
+		}
+		if err := write(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		if *dryRun {
+			printDiffs(os.Stdout, results)
+		}
+
+		if *recursive {
+			printSummary(os.Stdout, results)
+		} else if len(results[0].Changes) == 0 {
+			fmt.Println("No updates available.")
+		} else {
+			for _, update := range results[0].Changes {
+				fmt.Println(update)
+			}
+		}
+		printSkipped(os.Stdout, results)
+		printFailed(os.Stdout, results)
+	}
+
+	if moduleErr != nil {
+		fmt.Fprintf(os.Stderr, "Error updating modules: %v\n", contextError(ctx, moduleErr))
+		os.Exit(1)
+	}
+
+	// Providers that could not be checked fail the run once every other
+	// provider has been updated.
+	if hasFailures(results) {
+		os.Exit(1)
+	}
+
+	// In dry-run mode a distinct status lets CI treat stale providers as a
+	// failed check without modifying anything.
+	if *dryRun && hasChanges(results) {
+		os.Exit(exitUpdatesAvailable)
+	}
+}
+
+// exitUpdatesAvailable is the status returned by update --dry-run when at
+// least one constraint would change.
+const exitUpdatesAvailable = 2
+
+func hasFailures(results []updater.ModuleResult) bool {
+	for _, r := range results {
+		if len(r.Failed) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func hasChanges(results []updater.ModuleResult) bool {
+	for _, r := range results {
+		if len(r.Changes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// printDiffs writes a colored unified diff for every file the update would
+// rewrite.
+func printDiffs(out io.Writer, results []updater.ModuleResult) {
+	for _, r := range results {
+		for _, f := range r.Files {
+			name := filepath.ToSlash(f.Path)
+			d := diff.Unified("a/"+name, "b/"+name, f.Before, f.After, diff.DefaultContext)
+			fmt.Fprint(out, colorizeDiff(d))
+			fmt.Fprintln(out)
+		}
+	}
+}
+
+func colorizeDiff(d string) string {
+	lines := strings.Split(d, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			lines[i] = ui.TitleStyle.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = ui.DiffHunkStyle.Render(line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = ui.DiffAddStyle.Render(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = ui.DiffDelStyle.Render(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func loadConfig(path, targetDir string) (*config.Config, error) {
+	if path != "" {
+		return config.Load(path)
+	}
+	return config.LoadDefault(targetDir)
+}
+
+// clientFlags are the registry client flags shared by create and update.
+type clientFlags struct {
+	mirror      *string
+	releases    *string
+	refresh     *bool
+	concurrency *int
+	timeout     *time.Duration
+}
+
+// addClientFlags registers the client flags on fs. The --timeout given
+// before the command is the default of the command's own --timeout.
+func addClientFlags(fs *flag.FlagSet, timeout time.Duration) clientFlags {
+	return clientFlags{
+		mirror:      fs.String("mirror", "", "Provider mirror directory or network mirror URL to read versions from instead of the registry (default $"+providers.MirrorEnv+")"),
+		releases:    fs.String("releases", "", "Terraform or OpenTofu releases index file or URL to read instead of the public one (default $"+providers.ReleasesEnv+")"),
+		refresh:     fs.Bool("refresh", false, "Revalidate cached registry responses instead of using them"),
+		concurrency: fs.Int("concurrency", providers.DefaultMaxConcurrent, "Maximum number of registry requests in flight at once"),
+		timeout:     fs.Duration("timeout", timeout, "Give up on registry requests after this long, e.g. 30s (default no limit)"),
+	}
+}
+
+// context returns the context of a command: canceled on SIGINT or SIGTERM
+// and once --timeout has passed.
+func (cf clientFlags) context() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if *cf.timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, *cf.timeout, fmt.Errorf("timed out after %s", *cf.timeout))
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// contextError replaces err by the reason ctx ended, such as the timeout,
+// when it was caused by ctx.
+func contextError(ctx context.Context, err error) error {
+	if ctx.Err() == nil {
+		return err
+	}
+	cause := context.Cause(ctx)
+	if cause == context.Canceled {
+		return errors.New("interrupted")
+	}
+	return cause
+}
+
+// newClient returns a registry client for f, failing on credential files
+// that cannot be read rather than silently sending no token. A mirror or
+// releases index overrides the one of the environment; refresh revalidates
+// the cache.
+func (cf clientFlags) newClient(f flavor.Flavor) (*providers.Client, error) {
+	if *cf.concurrency < 1 {
+		return nil, fmt.Errorf("--concurrency must be at least 1")
+	}
+	creds, err := providers.LoadCredentials()
+	if err != nil {
+		return nil, fmt.Errorf("reading registry credentials: %w", err)
+	}
+	client := providers.NewClientFor(f)
+	client.Credentials = creds
+	client.MaxConcurrent = *cf.concurrency
+	if *cf.mirror != "" {
+		client.Mirror = *cf.mirror
+	}
+	if *cf.releases != "" {
+		client.Releases = *cf.releases
+	}
+	if *cf.refresh && client.Cache != nil {
+		client.Cache.Refresh = true
+	}
+	return client, nil
+}
+
+// newUpdater builds an updater whose policy comes from the --allow flag,
+// falling back to the settings file, with per-provider overrides applied on
+// top. Lock file platforms follow the same flag-over-file precedence.
+func newUpdater(cfg *config.Config, allow, flavorName string, cf clientFlags, platforms []string) (*updater.Updater, error) {
+	u := updater.NewUpdater()
+
+	if flavorName == "" {
+		flavorName = cfg.Flavor
+	}
+	f, err := flavor.Parse(flavorName)
+	if err != nil {
+		return nil, err
+	}
+	u.Flavor = f
+	if u.Client, err = cf.newClient(f); err != nil {
+		return nil, err
+	}
+
+	if allow == "" {
+		allow = cfg.Allow
+	}
+	policy, err := updater.ParsePolicy(allow)
+	if err != nil {
+		return nil, err
+	}
+	u.Policy = policy
+
+	u.Overrides = make(map[string]updater.Policy)
+	for source, a := range cfg.ProviderAllows() {
+		p, err := updater.ParsePolicy(a)
+		if err != nil {
+			return nil, fmt.Errorf("provider %q: %w", source, err)
+		}
+		u.Overrides[source] = p
+	}
+
+	if len(platforms) == 0 {
+		platforms = cfg.LockPlatforms
+	}
+	for _, name := range platforms {
+		p, err := providers.ParsePlatform(name)
+		if err != nil {
+			return nil, err
+		}
+		u.LockPlatforms = append(u.LockPlatforms, p)
+	}
+
+	return u, nil
+}
+
+// printSummary renders one row per changed provider, and a single row for
+// each module that was already up to date.
+func printSummary(out io.Writer, results []updater.ModuleResult) {
+	if len(results) == 0 {
+		fmt.Fprintln(out, "No Terraform modules with required_providers found.")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODULE\tPROVIDER\tFROM\tTO\tFILE")
+
+	updated := 0
+	for _, r := range results {
+		if len(r.Changes) == 0 {
+			fmt.Fprintf(w, "%s\t-\t-\t-\tup to date\n", r.Path)
+			continue
+		}
+		updated++
+		for _, c := range r.Changes {
+			label := c.Source
+			switch c.Kind {
+			case updater.KindModule:
+				label = "module." + c.Name
+			case updater.KindCore:
+				label = c.Name
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Path, label, c.From, c.To, filepath.Base(c.File))
+		}
+	}
+	w.Flush()
+
+	fmt.Fprintf(out, "\n%d of %d modules updated.\n", updated, len(results))
+}
+
+// printSkipped lists the releases held back by the update policy, major
+// upgrades first so they can be scheduled separately.
+func printSkipped(out io.Writer, results []updater.ModuleResult) {
+	var major, other []updater.Skip
+	for _, r := range results {
+		for _, s := range r.Skipped {
+			if s.Major {
+				major = append(major, s)
+			} else {
+				other = append(other, s)
+			}
+		}
+	}
+
+	for _, group := range []struct {
+		title string
+		skips []updater.Skip
+	}{
+		{"Skipped major upgrades:", major},
+		{"Skipped upgrades held back by policy:", other},
+	} {
+		if len(group.skips) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "\n%s\n", group.title)
+		for _, s := range group.skips {
+			fmt.Fprintf(out, "  %s: %s\n", s.Module, s)
+		}
+	}
+}
+
+// printFailed lists the providers and modules whose releases could not be
+// looked up.
+func printFailed(out io.Writer, results []updater.ModuleResult) {
+	first := true
+	for _, r := range results {
+		for _, f := range r.Failed {
+			if first {
+				fmt.Fprintln(out, "\nFailed:")
+				first = false
+			}
+			fmt.Fprintf(out, "  %s: %s\n", f.Module, f)
 		}
 	}
 }
 
 func printHelp() {
-	fmt.Println("Usage: tfinit <command> [directory]")
+	fmt.Println("Usage: tfinit [--timeout <duration>] <command> [directory]")
+	fmt.Println("\nGlobal flags:")
+	fmt.Println("  --timeout <duration>  give up on registry requests after this long, e.g. 30s (also accepted after the command)")
 	fmt.Println("\nCommands:")
 	fmt.Println("  create [name]   Create a new Terraform project in the specified directory (defaults to current dir)")
+	fmt.Println("                  --providers <list>  providers to include, e.g. aws,github")
+	fmt.Println("                  --alias <p.a,k=v>   aliased provider block, e.g. aws.us_east_1,region=us-east-1 (repeatable)")
+	fmt.Println("                  --yes               skip the interactive UI (implied when stdin is not a terminal)")
+	fmt.Println("                  --catalog <dir>     extra directory of provider definitions (repeatable)")
+	fmt.Println("                  --template <src>    directory or git URL of .tmpl files overriding the built-ins (repeatable)")
+	fmt.Println("                  --backend <type>    none, s3, s3-dynamodb, gcs, azurerm, cloud, http or local")
+	fmt.Println("                  --backend-config k=v  backend setting written to backend.hcl (repeatable)")
+	fmt.Println("                  --envs <list>       environments of the project (default dev)")
+	fmt.Println("                  --layout <layout>   single, tfvars (envs/<env>.tfvars), dirs (envs/<env>/) or workspaces")
+	fmt.Println("                  --flavor <name>     terraform (default) or tofu")
+	fmt.Println("                  --encryption        scaffold OpenTofu state encryption (tofu)")
+	fmt.Println("                  --tofu-files        write .tofu instead of .tf files (tofu)")
+	fmt.Println("                  --mirror <src>      provider mirror directory or URL, for offline use")
+	fmt.Println("                  --releases <src>    Terraform/OpenTofu releases index file or URL")
+	fmt.Println("                  --refresh           revalidate cached registry responses")
+	fmt.Println("                  --concurrency <n>   registry requests in flight at once (default 8)")
+	fmt.Println("  add <provider>...     Add providers to an existing project, e.g. tfinit add cloudflare")
+	fmt.Println("                  --name <dir>        project directory (defaults to current dir)")
+	fmt.Println("                  --catalog <dir>     extra directory of provider definitions (repeatable)")
+	fmt.Println("                  --flavor <name>     terraform (default) or tofu")
+	fmt.Println("                  --mirror <src>      provider mirror directory or URL, for offline use")
+	fmt.Println("  remove <provider>...  Remove unused providers from an existing project, e.g. tfinit remove vercel")
+	fmt.Println("                  --name <dir>        project directory (defaults to current dir)")
+	fmt.Println("                  --catalog <dir>     extra directory of provider definitions (repeatable)")
 	fmt.Println("  update [name]   Update providers in an existing project (defaults to current dir)")
-}
+	fmt.Println("                  --recursive      update every module below the directory")
+	fmt.Println("                  --ignore <glob>  skip matching directories in recursive mode")
+	fmt.Println("                  --allow <level>  largest bump allowed: patch, minor or major")
+	fmt.Println("                  --config <file>  settings file (default <dir>/.tfinit.hcl)")
+	fmt.Println("                  --dry-run        show a diff instead of writing; exit 2 if stale")
+	fmt.Println("                  --output <fmt>   text (default), json or sarif")
+	fmt.Println("                  --lock=false     leave .terraform.lock.hcl untouched")
+	fmt.Println("                  --platform <p>   os_arch hashed into the lock file (repeatable)")
+	fmt.Println("                  --modules        also update module versions and git tags")
+	fmt.Println("                  --required-version  raise required_version floors, checking provider protocols")
+	fmt.Println("                  --flavor <name>  terraform (default) or tofu")
+	fmt.Println("                  --mirror <src>   provider mirror directory or URL, for offline use")
+	fmt.Println("                  --releases <src> Terraform/OpenTofu releases index file or URL")
+	fmt.Println("                  --refresh        revalidate cached registry responses")
+	fmt.Println("                  --concurrency <n> registry requests in flight at once (default 8)")
+}
\ No newline at end of file
@@ -61,8 +61,16 @@ Feature: CLI Commands for Create and Update
     Then it should return an error "directory exists and is not empty"
 
   @unit
-  Scenario: Update command fails if provider.tf is missing
+  Scenario: Update command reads required_providers from any .tf file
+    Given I have a Terraform project in "split-infra"
+    And the "versions.tf" contains "hashicorp/aws" version "4.0.0"
+    And the latest version of "hashicorp/aws" is "5.0.0"
+    When I run "tfinit update split-infra"
+    Then the file "split-infra/versions.tf" should show version "5.0.0"
+
+  @unit
+  Scenario: Update command fails if no .tf files are present
     Given the directory "empty-dir" exists
-    And "empty-dir" does not contain "provider.tf"
+    And "empty-dir" does not contain any ".tf" files
     When I run "tfinit update empty-dir"
-    Then it should return an error "provider.tf not found"
+    Then it should return an error "no .tf files found"